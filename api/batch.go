@@ -1,20 +1,19 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 )
 
-// 创建结果保存目录
-func createOutputDir() string {
+// CreateOutputDir 创建并返回结果保存目录 outputs/<日期>/
+func CreateOutputDir() string {
 	baseDir := "outputs"
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		log.Fatalf("创建输出目录失败: %v", err)
@@ -44,8 +43,8 @@ func downloadFile(url, savePath string) error {
 	return nil
 }
 
-// 记录任务日志
-func logTaskInfo(dir string, taskID string, outputs []TaskOutput) error {
+// 记录任务日志，seed 为任务使用的随机种子，可为 nil
+func logTaskInfo(dir string, taskID string, seed *int64, outputs []TaskOutput) error {
 	logFile := filepath.Join(dir, "task.log")
 	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -55,6 +54,9 @@ func logTaskInfo(dir string, taskID string, outputs []TaskOutput) error {
 	logger := log.New(file, "", log.LstdFlags)
 	logger.Printf("任务ID: %s", taskID)
 	logger.Printf("执行时间: %s", time.Now().Format("2006-01-02 15:04:05"))
+	if seed != nil {
+		logger.Printf("随机种子: %d", *seed)
+	}
 	logger.Println("生成结果:")
 	for _, output := range outputs {
 		logger.Printf("- 文件URL: %s", output.FileUrl)
@@ -66,13 +68,16 @@ func logTaskInfo(dir string, taskID string, outputs []TaskOutput) error {
 	return nil
 }
 
-// SaveTaskOutputs 保存任务输出结果到指定目录
-func SaveTaskOutputs(outputDir, taskID string, outputs []TaskOutput, imageBaseName string) {
+// SaveTaskOutputs 保存任务输出结果到指定目录，返回成功保存的本地文件路径
+func SaveTaskOutputs(outputDir, taskID string, outputs []TaskOutput, imageBaseName string) []string {
+	return saveTaskOutputs(outputDir, taskID, outputs, imageBaseName, nil)
+}
+
+// saveTaskOutputs 同 SaveTaskOutputs，seed 不为 nil 时记录到任务日志中
+func saveTaskOutputs(outputDir, taskID string, outputs []TaskOutput, imageBaseName string, seed *int64) []string {
+	var saved []string
 	for i, output := range outputs {
-		fmt.Printf("[批量] - 文件URL: %s\n", output.FileUrl)
-		fmt.Printf("[批量]   类型: %s\n", output.FileType)
-		fmt.Printf("[批量]   节点ID: %s\n", output.NodeId)
-		fmt.Printf("[批量]   任务耗时: %s\n", output.TaskCostTime)
+		logInfo("[批量] 生成结果", "taskId", taskID, "fileUrl", output.FileUrl, "fileType", output.FileType, "nodeId", output.NodeId, "taskCostTime", output.TaskCostTime)
 
 		var fileName string
 		if imageBaseName != "" {
@@ -82,97 +87,381 @@ func SaveTaskOutputs(outputDir, taskID string, outputs []TaskOutput, imageBaseNa
 		}
 		savePath := filepath.Join(outputDir, fileName)
 		if err := downloadFile(output.FileUrl, savePath); err != nil {
-			fmt.Printf("[批量] 下载文件失败: %v\n", err)
+			logError("[批量] 下载文件失败", "fileUrl", output.FileUrl, "error", err)
 			continue
 		}
-		fmt.Printf("[批量]   已保存到: %s\n", savePath)
+		logInfo("[批量] 已保存", "path", savePath)
+		saved = append(saved, savePath)
 	}
 	// 记录任务日志
-	if err := logTaskInfo(outputDir, taskID, outputs); err != nil {
-		fmt.Printf("[批量] 记录任务日志失败: %v\n", err)
+	if err := logTaskInfo(outputDir, taskID, seed, outputs); err != nil {
+		logError("[批量] 记录任务日志失败", "error", err)
 	}
+	return saved
+}
+
+// DownloadTaskOutputs 查询已完成任务的生成结果，按工作流的输出筛选规则保存到 outputDir 并记录任务日志，
+// 文件命名和日志与新任务完成时相同，可用于收集在网页端或其他机器上创建的任务的结果
+// outputDir 为空时保存到 outputs/<日期>/，baseName 为空时用任务ID命名；seed 为任务的随机种子（如 TaskRecord.Seed），不为 nil 时记录到任务日志中
+// 部分文件下载失败时同时返回结果和错误
+func (we *WorkflowExecutor) DownloadTaskOutputs(taskID, workflowID, outputDir, baseName string, seed *int64) (*TaskResult, error) {
+	resp, err := QueryTaskOutputs(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		// 任务未完成或已失败时接口返回非 0 的 code
+		return nil, fmt.Errorf("code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	if outputDir == "" {
+		outputDir = CreateOutputDir()
+	} else if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %v", err)
+	}
+
+	result := &TaskResult{Label: taskID, WorkflowID: workflowID, TaskID: taskID, Status: "SUCCESS", Seed: seed, Started: time.Now()}
+	result.Outputs = we.OutputSelector(workflowID).Apply(resp.Data)
+	result.Skipped = len(resp.Data) - len(result.Outputs)
+	if result.Skipped > 0 {
+		logInfo("按筛选规则跳过部分输出", "taskId", taskID, "kept", len(result.Outputs), "skipped", result.Skipped)
+	}
+	result.Coins = taskCoins(resp.Data)
+	result.Files = saveTaskOutputs(outputDir, taskID, result.Outputs, baseName, seed)
+	result.Duration = time.Since(result.Started)
+	if len(result.Files) < len(result.Outputs) {
+		return result, fmt.Errorf("%d 个文件下载失败", len(result.Outputs)-len(result.Files))
+	}
+	return result, nil
+}
+
+// BatchOptions 批量处理选项
+type BatchOptions struct {
+	Concurrency int          // 并发数量
+	Inputs      InputOptions // 输入文件发现选项
+	Disposition Disposition  // 任务结束后输入文件的处理策略
 }
 
 // BatchProcessInputs 批量处理 inputs 目录下的图片文件
 func BatchProcessInputs(workflowID string, concurrency int, executor *WorkflowExecutor) error {
-	inputDir := "inputs"
-	tmpDir := "tmp"
-	outputDir := createOutputDir()
+	_, err := BatchProcessInputsWithOptions(workflowID, BatchOptions{
+		Concurrency: concurrency,
+		Inputs:      DefaultInputOptions(),
+		Disposition: DefaultDisposition(),
+	}, executor)
+	return err
+}
 
-	// 创建 tmp 目录
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return fmt.Errorf("创建 tmp 目录失败: %v", err)
+// BatchProcessInputsWithOptions 按选项批量处理输入目录下的文件，返回本次运行记录
+// 输入文件位于子目录时，输出结果保存在输出目录下相同的子目录中；没有输入文件时返回 nil
+func BatchProcessInputsWithOptions(workflowID string, opts BatchOptions, executor *WorkflowExecutor) (*RunRecord, error) {
+	config, exists := executor.manager.GetWorkflow(workflowID)
+	if !exists {
+		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
 	}
 
-	// 获取所有文件
-	files, err := ioutil.ReadDir(inputDir)
-	if err != nil {
-		return fmt.Errorf("读取 inputs 目录失败: %v", err)
+	inputOpts := opts.Inputs
+	if len(inputOpts.Kinds) == 0 {
+		inputOpts.Kinds = config.InputKinds()
 	}
-
-	// 过滤文件
-	var inputFiles []string
-	for _, file := range files {
-		if !file.IsDir() {
-			ext := strings.ToLower(filepath.Ext(file.Name()))
-			if ext == ".png" || ext == ".jpg" || ext == ".mp4" {
-				inputFiles = append(inputFiles, filepath.Join(inputDir, file.Name()))
-			}
-		}
+	if opts.Disposition.Mode == DispositionMark {
+		inputOpts.SkipDone = true
+	}
+	inputFiles, err := DiscoverInputs(inputOpts)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("共获取到 %d 个文件：\n", len(inputFiles))
+	logInfo("[批量] 获取输入文件", "dir", inputOpts.Dir, "count", len(inputFiles))
 	for _, file := range inputFiles {
-		fmt.Println("  -", file)
+		logDebug("[批量] 输入文件", "path", file.Path, "kind", file.Kind, "size", file.Size)
 	}
 
 	if len(inputFiles) == 0 {
-		fmt.Println("inputs 目录下没有支持的文件（支持 .png、.jpg、.mp4）。")
-		return nil
+		logWarn("输入目录下没有支持的文件", "dir", inputOpts.Dir, "kinds", inputOpts.Kinds)
+		return nil, nil
 	}
 
+	run := newRunRecord(RunKindImages, workflowID, CreateOutputDir(), len(inputFiles))
+	run.start(executor)
+	runConcurrent(opts.Concurrency, len(inputFiles), func(i int) {
+		input := inputFiles[i]
+		result := processInput(workflowID, input, run.OutputDir, executor)
+		disposeInput("[批量]", opts.Disposition, input, result)
+		run.Tasks[i] = result
+	})
+	run.finish(executor)
+
+	logInfo("批量处理完成")
+	return run, nil
+}
+
+// runConcurrent 以最多 concurrency 个并发执行 count 个任务，全部完成后返回
+func runConcurrent(concurrency, count int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	sem := make(chan struct{}, concurrency)
 	var wg sync.WaitGroup
-
-	for _, imgPath := range inputFiles {
+	for i := 0; i < count; i++ {
 		sem <- struct{}{}
 		wg.Add(1)
-		go func(img string) {
+		go func(i int) {
 			defer wg.Done()
-			fmt.Printf("[批量] 开始处理: %s\n", img)
-			fmt.Printf("[批量] 上传图片: %s\n", img)
-			imageBaseName := strings.TrimSuffix(filepath.Base(img), filepath.Ext(img))
-			resp, err := executor.ExecuteWorkflowWithImage(workflowID, img)
-			if err != nil {
-				fmt.Printf("[批量] 处理失败: %s, 错误: %v\n", img, err)
-				<-sem
-				return
-			} else if resp.Code != 0 || resp.Data.TaskId == "" {
-				fmt.Printf("[批量] 任务创建失败: %s, code: %d, msg: %s\n", img, resp.Code, resp.Msg)
-				<-sem
-				return
-			} else {
-				fmt.Printf("[批量] 任务创建成功: %s, 任务ID: %s\n", img, resp.Data.TaskId)
-				fmt.Printf("[批量] 等待任务完成: %s, 任务ID: %s\n", img, resp.Data.TaskId)
-				err := executor.MonitorTask(resp.Data.TaskId, func(outputResp *TaskOutputResponse) {
-					SaveTaskOutputs(outputDir, resp.Data.TaskId, outputResp.Data, imageBaseName)
-				})
-				if err != nil {
-					fmt.Printf("[批量] 任务监控失败: %s, 错误: %v\n", img, err)
-				}
-				// 任务完成后立即移动文件
-				dst := filepath.Join(tmpDir, filepath.Base(img))
-				if err := os.Rename(img, dst); err != nil {
-					fmt.Printf("[批量] 移动文件失败: %s -> %s, 错误: %v\n", img, dst, err)
-				} else {
-					fmt.Printf("[批量] 已移动到: %s\n", dst)
-				}
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// disposeInput 任务结束后按策略处理输入文件，记录新位置到任务结果中
+func disposeInput(tag string, disposition Disposition, input InputFile, result *TaskResult) {
+	if result.Interrupted() {
+		// 未完成的任务保留输入文件，便于恢复或重新提交
+		return
+	}
+	dst, err := disposition.Apply(input, result.Succeeded())
+	if err != nil {
+		logError(tag+" 处理输入文件失败", "input", input.Path, "mode", disposition.Mode, "error", err)
+	} else if dst != "" {
+		result.InputMoved = dst
+		logInfo(tag+" 输入文件已处理", "input", input.Path, "mode", disposition.Mode, "dst", dst)
+	}
+}
+
+// processInput 执行单个输入文件的任务并保存结果
+func processInput(workflowID string, input InputFile, outputDir string, executor *WorkflowExecutor) *TaskResult {
+	return runTask(taskJob{
+		tag:   "[批量]",
+		label: input.Path,
+		// 保持输入文件的子目录结构
+		outputDir: filepath.Join(outputDir, input.RelDir()),
+		baseName:  input.BaseName(),
+		inputs:    []string{input.Path},
+		create: func() (*TaskCreateResponse, error) {
+			return executor.ExecuteWorkflowWithImage(workflowID, input.Path)
+		},
+	}, executor)
+}
+
+// TaskResult 单个任务的执行结果
+type TaskResult struct {
+	Label        string        `json:"label"`                  // 任务标识，如输入文件路径
+	WorkflowID   string        `json:"workflowId,omitempty"`   // 工作流ID
+	TaskID       string        `json:"taskId,omitempty"`       // 任务ID，任务创建失败时为空；重新提交过时为最后一次的任务ID
+	Attempts     []string      `json:"attempts,omitempty"`     // 重新提交过时依次为每次提交的任务ID
+	Status       string        `json:"status"`                 // 最终状态: SUCCESS, FAILED, TIMEOUT, ERROR, CANCELED, SKIPPED, DETACHED
+	Error        string        `json:"error,omitempty"`        // 错误信息
+	Reason       string        `json:"reason,omitempty"`       // 失败原因: auth、budget，其他错误为空
+	Inputs       []string      `json:"inputs,omitempty"`       // 本地输入文件
+	InputMoved   string        `json:"inputMoved,omitempty"`   // 任务结束后输入文件的新位置
+	NodeInfoList []NodeInfo    `json:"nodeInfoList,omitempty"` // 提交的节点参数
+	Seed         *int64        `json:"seed,omitempty"`         // 节点参数中的随机种子，同时出现在结果文件名中
+	Outputs      []TaskOutput  `json:"outputs,omitempty"`      // 服务器返回并经筛选规则保留的生成结果
+	Skipped      int           `json:"skipped,omitempty"`      // 被筛选规则过滤掉的输出数量
+	Files        []string      `json:"files,omitempty"`        // 已保存的本地文件
+	Coins        Coins         `json:"coins,omitempty"`        // 消耗的金币
+	Profile      string        `json:"profile,omitempty"`      // 使用 Key 池时创建任务的账户
+	Hooks        []HookResult  `json:"hooks,omitempty"`        // 任务结束后执行的钩子
+	Started      time.Time     `json:"started"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// TaskStatusError 表示任务在本地出错（上传失败、创建失败、监控失败等），未能拿到服务器的最终状态
+const TaskStatusError = "ERROR"
+
+// TaskStatusCanceled 任务已被取消
+const TaskStatusCanceled = "CANCELED"
+
+// TaskStatusSkipped 任务未提交（如运行被中断）
+const TaskStatusSkipped = "SKIPPED"
+
+// Succeeded 判断任务是否成功
+func (r *TaskResult) Succeeded() bool {
+	return r.Status == "SUCCESS"
+}
+
+// taskJob 待执行的单个任务
+type taskJob struct {
+	tag       string                              // 日志前缀
+	label     string                              // 日志中的任务标识
+	outputDir string                              // 结果保存目录
+	baseName  string                              // 结果文件名前缀
+	inputs    []string                            // 本地输入文件，记录到任务结果中
+	selector  *OutputSelector                     // 输出筛选规则，为空时使用执行器或工作流的配置
+	create    func() (*TaskCreateResponse, error) // 创建任务
+	onCreated func(result *TaskResult)            // 任务创建成功、开始等待结果前调用，重新提交后再次调用，可为空
+	canceled  func() bool                         // 任务已被请求取消时返回 true，执行失败后不再重新提交，可为空
+	allFiles  bool                                // 保留的输出必须全部下载成功，否则任务记为 ERROR（结果文件与输出需要一一对应时）
+	rerunOf   string                              // 由 rerun 重新提交时为原任务ID，记录到任务登记表中
+}
+
+// TaskOptions 单个任务的执行参数
+type TaskOptions struct {
+	Label     string                              // 日志和任务登记表中的任务标识，如输入文件路径
+	OutputDir string                              // 结果保存目录，为空时为 outputs/<日期>/
+	BaseName  string                              // 结果文件名前缀，为空时使用任务ID，有随机种子时追加 _seed<种子>
+	Inputs    []string                            // 本地输入文件，记录到任务结果中
+	RerunOf   string                              // 由 rerun 重新提交时为原任务ID
+	Create    func() (*TaskCreateResponse, error) // 创建任务
+	OnCreated func(result *TaskResult)            // 任务创建成功、开始等待结果前调用，重新提交后再次调用，可为空
+}
+
+// RunTask 创建单个任务、等待完成并保存结果，流程与批量处理中的每个任务相同：
+// 失败时按重试规则重新提交，超时取消，结束后通知并登记任务
+func (we *WorkflowExecutor) RunTask(opts TaskOptions) *TaskResult {
+	if opts.OutputDir == "" {
+		opts.OutputDir = CreateOutputDir()
+	}
+	return runTask(taskJob{
+		tag:       "[任务]",
+		label:     opts.Label,
+		outputDir: opts.OutputDir,
+		baseName:  opts.BaseName,
+		inputs:    opts.Inputs,
+		create:    opts.Create,
+		onCreated: opts.OnCreated,
+		rerunOf:   opts.RerunOf,
+	}, we)
+}
+
+// runTask 创建任务、等待完成并把结果保存到 job.outputDir
+func runTask(job taskJob, executor *WorkflowExecutor) *TaskResult {
+	tag, label := job.tag, job.label
+	result := &TaskResult{Label: label, Status: TaskStatusError, Inputs: job.inputs, Started: time.Now()}
+	if executor.Interrupted() {
+		// 运行已被中断，不再提交新任务
+		result.Status = TaskStatusSkipped
+		return result
+	}
+	defer func() {
+		result.Duration = time.Since(result.Started)
+		// 停止等待的任务尚未结束，不发送结束通知，登记表中保留最近一次得知的状态
+		// 先通知再登记，钩子的执行结果随任务一起登记
+		if result.Status != TaskStatusDetached {
+			executor.notifyTask(result)
+			recordJob(job, result, "")
+		}
+	}()
+	fail := func(msg string, err error, args ...any) *TaskResult {
+		result.Error = err.Error()
+		result.Reason = FailureReason(err)
+		logError(tag+" "+msg, append([]any{"input", label, "error", err}, args...)...)
+		return result
+	}
+
+	logInfo(tag+" 开始处理", "input", label)
+	progress := func(status string) {
+		executor.notifyProgress(TaskProgress{Label: label, TaskID: result.TaskID, Status: status, Started: result.Started})
+		recordJob(job, result, status)
+	}
+	progress(TaskStatusSubmitting)
+	resp, err := job.create()
+	if err != nil {
+		return fail("处理失败", err)
+	}
+	result.WorkflowID = resp.WorkflowId
+	result.NodeInfoList = resp.NodeInfoList
+	if resp.Code != 0 || resp.Data.TaskId == "" {
+		return fail("任务创建失败", fmt.Errorf("code: %d, msg: %s", resp.Code, resp.Msg))
+	}
+	result.TaskID = resp.Data.TaskId
+	result.Profile = resp.Profile
+	result.Seed = executor.TaskSeed(resp.WorkflowId, resp.NodeInfoList)
+	logInfo(tag+" 任务创建成功，等待任务完成", "input", label, "taskId", resp.Data.TaskId, "profile", resp.Profile)
+	recordJob(job, result, "QUEUED")
+	if job.onCreated != nil {
+		job.onCreated(result)
+	}
+
+	if err := os.MkdirAll(job.outputDir, 0755); err != nil {
+		return fail("创建输出目录失败", err, "dir", job.outputDir)
+	}
+	retry := executor.RetryPolicy(resp.WorkflowId)
+	timeout := executor.TaskTimeout(resp.WorkflowId)
+	for attempt := 1; ; attempt++ {
+		// 任务结束后 Key 池不再记录任务的账户，重新提交需要的 Key 先取出
+		apiKey := taskApiKey(result.TaskID)
+		err = executor.monitorTask(result.TaskID, timeout, progress, func(outputResp *TaskOutputResponse) {
+			selector := job.selector
+			if selector == nil {
+				selector = executor.OutputSelector(resp.WorkflowId)
+			}
+			result.Outputs = selector.Apply(outputResp.Data)
+			result.Skipped = len(outputResp.Data) - len(result.Outputs)
+			if result.Skipped > 0 {
+				logInfo(tag+" 按筛选规则跳过部分输出", "input", label, "kept", len(result.Outputs), "skipped", result.Skipped)
 			}
-			<-sem
-		}(imgPath)
+			result.Coins = taskCoins(outputResp.Data)
+			baseName := job.baseName
+			if result.Seed != nil {
+				baseName = OutputBaseName(job.baseName, result.TaskID, result.Seed)
+			}
+			result.Files = saveTaskOutputs(job.outputDir, result.TaskID, result.Outputs, baseName, result.Seed)
+		})
+		if !retry.retryable(err) || attempt > retry.Attempts ||
+			executor.Interrupted() || (job.canceled != nil && job.canceled()) {
+			break
+		}
+
+		// 服务器上执行失败或超时，用相同的节点参数重新提交
+		failedStatus := "FAILED"
+		if errors.Is(err, ErrTaskTimeout) {
+			failedStatus = TaskStatusTimeout
+		}
+		logWarn(tag+" 任务未成功，重新提交", "input", label, "taskId", result.TaskID, "status", failedStatus, "attempt", attempt+1, "maxAttempts", retry.Attempts+1, "delay", retry.Delay)
+		recordJob(job, result, failedStatus)
+		if len(result.Attempts) == 0 {
+			result.Attempts = []string{result.TaskID}
+		}
+		next, retryErr := executor.resubmit(result, apiKey, retry)
+		if retryErr != nil {
+			err = fmt.Errorf("%w（重新提交失败: %v）", err, retryErr)
+			break
+		}
+		result.TaskID = next.Data.TaskId
+		result.Profile = next.Profile
+		result.NodeInfoList = next.NodeInfoList
+		result.Seed = executor.TaskSeed(next.WorkflowId, next.NodeInfoList)
+		result.Attempts = append(result.Attempts, result.TaskID)
+		logInfo(tag+" 任务已重新提交，等待任务完成", "input", label, "taskId", result.TaskID, "attempt", attempt+1, "profile", result.Profile)
+		recordJob(job, result, "QUEUED")
+		if job.onCreated != nil {
+			job.onCreated(result)
+		}
+	}
+	if errors.Is(err, ErrTaskFailed) {
+		result.Status = "FAILED"
+		return fail("任务执行失败", err, "taskId", result.TaskID)
+	}
+	if errors.Is(err, ErrTaskTimeout) {
+		result.Status = TaskStatusTimeout
+		return fail("任务超时", err, "taskId", result.TaskID)
+	}
+	if errors.Is(err, ErrTaskCanceled) || errors.Is(err, ErrTaskDetached) {
+		result.Status, result.Error = TaskStatusCanceled, err.Error()
+		if errors.Is(err, ErrTaskDetached) {
+			result.Status = TaskStatusDetached
+		}
+		return result
+	}
+	if err != nil {
+		return fail("任务监控失败", err, "taskId", result.TaskID)
 	}
+	if job.allFiles && len(result.Files) != len(result.Outputs) {
+		return fail("下载生成结果失败", fmt.Errorf("只下载了 %d/%d 个输出文件", len(result.Files), len(result.Outputs)), "taskId", result.TaskID)
+	}
+	result.Status = "SUCCESS"
+	return result
+}
 
-	wg.Wait()
-	fmt.Println("批量处理完成。")
-	return nil
+// taskCoins 返回任务消耗的金币，各输出重复携带同一任务的消耗时取其中最大值
+func taskCoins(outputs []TaskOutput) Coins {
+	var coins Coins
+	for _, output := range outputs {
+		if output.ConsumeCoins > coins {
+			coins = output.ConsumeCoins
+		}
+	}
+	return coins
 }
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 配置相关的环境变量
+const (
+	EnvApiKey  = "RUNNINGHUB_API_KEY" // 直接指定 API Key，优先于配置文件
+	EnvProfile = "RUNNINGHUB_PROFILE" // 指定使用的 profile
+)
+
+// DefaultProfile 配置文件顶层的 api_key 属于该 profile，未指定 profile 时使用
+const DefaultProfile = "default"
+
+// ProjectConfigFile 当前目录下的项目配置文件
+const ProjectConfigFile = "runninghub.yaml"
+
+// ApiKey 当前使用的 API Key，启动时根据配置设置
+var ApiKey string
+
+// GetApiKey 返回当前使用的 API Key
+func GetApiKey() string {
+	return ApiKey
+}
+
+// SetApiKey 设置当前使用的 API Key
+func SetApiKey(key string) {
+	ApiKey = strings.TrimSpace(key)
+}
+
+// ErrNoApiKey 没有找到可用的 API Key
+var ErrNoApiKey = errors.New("未找到 API Key")
+
+// Profile 一个账户的配置
+type Profile struct {
+	Name     string // profile 名称
+	ApiKey   string // API Key
+	Source   string // 定义 API Key 的配置文件
+	MaxTasks int    // 使用 Key 池时该账户同时运行的最大任务数，0 表示不限制
+}
+
+// Config 合并后的配置
+//
+// 配置文件格式:
+//
+//	profile: work          # 默认使用的 profile，可选
+//	api_key: xxxx          # 等价于 profiles.default.api_key
+//	pool: [work, personal] # 未指定 profile 时在这些账户间分配任务，可选
+//	profiles:
+//	  work:
+//	    api_key: xxxx
+//	    max_tasks: 3       # 使用 Key 池时的最大同时任务数，可选
+//	  personal:
+//	    api_key: yyyy
+type Config struct {
+	Profile  string              // 配置文件中指定的默认 profile
+	Pool     []string            // 配置文件中指定的 Key 池 profile
+	Profiles map[string]*Profile // profile 名称 -> 配置
+	Files    []string            // 已加载的配置文件，按优先级从低到高
+}
+
+// ConfigPaths 返回默认配置文件路径，按优先级从低到高:
+// 用户配置 ~/.config/runninghub/config.yaml（设置了 XDG_CONFIG_HOME 时位于该目录下），然后是当前目录的 runninghub.yaml
+func ConfigPaths() []string {
+	var paths []string
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "runninghub", "config.yaml"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "runninghub", "config.yaml"))
+	}
+	return append(paths, ProjectConfigFile)
+}
+
+// LoadConfig 依次加载默认配置文件和 path 指定的配置文件，后加载的覆盖先加载的同名配置
+// 默认配置文件不存在时跳过，path 指定的文件不存在时返回错误
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+	for _, p := range ConfigPaths() {
+		if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := cfg.load(p); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" {
+		if err := cfg.load(path); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// load 加载一个配置文件并合并到 cfg
+func (c *Config) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+
+	for key, value := range values {
+		switch key {
+		case "profile":
+			name, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("配置文件 %s: profile 应为字符串", path)
+			}
+			c.Profile = name
+		case "pool":
+			switch v := value.(type) {
+			case []string:
+				c.Pool = v
+			case string:
+				c.Pool = splitProfiles(v)
+			default:
+				return fmt.Errorf("配置文件 %s: pool 应为 profile 列表", path)
+			}
+		case "api_key", "apiKey", "apikey":
+			if err := c.setProfile(DefaultProfile, value, path); err != nil {
+				return err
+			}
+		case "profiles":
+			profiles, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("配置文件 %s: profiles 应为映射", path)
+			}
+			for name, pv := range profiles {
+				fields, ok := pv.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("配置文件 %s: profile %s 应为映射", path, name)
+				}
+				for field, fv := range fields {
+					switch field {
+					case "api_key", "apiKey", "apikey":
+						if err := c.setProfile(name, fv, path); err != nil {
+							return err
+						}
+					case "max_tasks", "maxTasks":
+						str, _ := fv.(string)
+						n, err := strconv.Atoi(str)
+						if err != nil || n < 0 {
+							return fmt.Errorf("配置文件 %s: profile %s 的 max_tasks 应为非负整数", path, name)
+						}
+						c.profile(name).MaxTasks = n
+					default:
+						return fmt.Errorf("配置文件 %s: profile %s 中未知的字段: %s", path, name, field)
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("配置文件 %s: 未知的字段: %s", path, key)
+		}
+	}
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// setProfile 设置 profile 的 API Key
+func (c *Config) setProfile(name string, value interface{}, path string) error {
+	key, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("配置文件 %s: profile %s 的 api_key 应为字符串", path, name)
+	}
+	p := c.profile(name)
+	p.ApiKey, p.Source = strings.TrimSpace(key), path
+	return nil
+}
+
+// profile 返回指定名称的 profile，不存在时创建
+func (c *Config) profile(name string) *Profile {
+	p, ok := c.Profiles[name]
+	if !ok {
+		p = &Profile{Name: name}
+		c.Profiles[name] = p
+	}
+	return p
+}
+
+// ProfileNames 返回已配置的 profile 名称
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveApiKey 按优先级确定使用的 API Key，返回 Key 和来源说明:
+//  1. profile 参数（命令行 -profile）指定的 profile，不存在时返回错误
+//  2. 环境变量 RUNNINGHUB_API_KEY
+//  3. 环境变量 RUNNINGHUB_PROFILE 或配置文件 profile 字段指定的 profile
+//  4. default profile
+func (c *Config) ResolveApiKey(profile string) (string, string, error) {
+	if profile != "" {
+		return c.profileKey(profile)
+	}
+	if key := strings.TrimSpace(os.Getenv(EnvApiKey)); key != "" {
+		return key, "环境变量 " + EnvApiKey, nil
+	}
+	if name := os.Getenv(EnvProfile); name != "" {
+		return c.profileKey(name)
+	}
+	if c.Profile != "" {
+		return c.profileKey(c.Profile)
+	}
+	if p, ok := c.Profiles[DefaultProfile]; ok && p.ApiKey != "" {
+		return p.ApiKey, p.Source, nil
+	}
+	return "", "", c.missingKeyError()
+}
+
+// ResolvePool 确定 Key 池使用的 profile，不使用 Key 池时返回 nil:
+//   - profile 参数（命令行 -profile）为逗号分隔的多个 profile 时使用这些 profile
+//   - 未指定 profile、未设置环境变量 RUNNINGHUB_API_KEY 和 RUNNINGHUB_PROFILE 时，使用配置文件的 pool 字段
+//
+// 只有一个 profile 时不使用 Key 池，由 ResolveApiKey 确定 API Key
+func (c *Config) ResolvePool(profile string) ([]*Profile, error) {
+	var names []string
+	switch {
+	case strings.Contains(profile, ","):
+		names = splitProfiles(profile)
+	case profile != "":
+		return nil, nil
+	case os.Getenv(EnvApiKey) != "" || os.Getenv(EnvProfile) != "":
+		return nil, nil
+	default:
+		names = c.Pool
+	}
+	if len(names) < 2 {
+		return nil, nil
+	}
+
+	var profiles []*Profile
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, _, err := c.profileKey(name); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, c.Profiles[name])
+	}
+	return profiles, nil
+}
+
+// splitProfiles 解析逗号分隔的 profile 列表
+func splitProfiles(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// profileKey 返回指定 profile 的 API Key
+func (c *Config) profileKey(name string) (string, string, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return "", "", fmt.Errorf("profile 不存在: %s（未找到任何配置文件）", name)
+		}
+		return "", "", fmt.Errorf("profile 不存在: %s（可用: %s）", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	if p.ApiKey == "" {
+		return "", "", fmt.Errorf("profile %s 未设置 api_key（%s）", name, p.Source)
+	}
+	return p.ApiKey, fmt.Sprintf("%s 中的 profile %s", p.Source, name), nil
+}
+
+// missingKeyError 说明可以设置 API Key 的位置
+func (c *Config) missingKeyError() error {
+	searched := "无"
+	if len(c.Files) > 0 {
+		searched = strings.Join(c.Files, ", ")
+	}
+	return fmt.Errorf("%w，请通过以下任一方式设置:\n"+
+		"  - 环境变量 %s=<your-api-key>\n"+
+		"  - 配置文件 %s 中写入 api_key: <your-api-key>\n"+
+		"  - -config <文件> 指定配置文件\n"+
+		"已加载的配置文件: %s",
+		ErrNoApiKey, EnvApiKey, strings.Join(ConfigPaths(), " 或 "), searched)
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig 完整的配置文件
+const testConfig = `
+profile: work          # 默认使用的 profile
+api_key: "default-key" # default profile
+pool: [work, personal]
+profiles:
+  work:
+    api_key: 'work-key'
+    max_tasks: 3
+  personal:
+    api_key: personal-key
+  empty:
+    max_tasks: 1
+`
+
+// loadTestConfig 只加载 content 写成的配置文件，不读取用户和当前目录的配置文件，并清除相关环境变量
+func loadTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv(EnvApiKey, "")
+	t.Setenv(EnvProfile, "")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig 返回错误: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg := loadTestConfig(t, testConfig)
+	if cfg.Profile != "work" || strings.Join(cfg.Pool, ",") != "work,personal" {
+		t.Errorf("profile = %q, pool = %v", cfg.Profile, cfg.Pool)
+	}
+	if got := strings.Join(cfg.ProfileNames(), ","); got != "default,empty,personal,work" {
+		t.Errorf("ProfileNames = %s", got)
+	}
+	work := cfg.Profiles["work"]
+	if work.ApiKey != "work-key" || work.MaxTasks != 3 || work.Source != cfg.Files[0] {
+		t.Errorf("work = %+v", work)
+	}
+	if got := cfg.Profiles[DefaultProfile].ApiKey; got != "default-key" {
+		t.Errorf("default api_key = %q", got)
+	}
+}
+
+// TestLoadConfigOverride -config 指定的文件覆盖用户配置文件中的同名配置
+func TestLoadConfigOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	user := filepath.Join(dir, "runninghub", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(user), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(user, []byte("api_key: user-key\nprofiles:\n  work:\n    api_key: user-work\n"), 0600)
+	override := filepath.Join(dir, "override.yaml")
+	os.WriteFile(override, []byte("profiles:\n  work:\n    api_key: override-work\n"), 0600)
+
+	cfg, err := LoadConfig(override)
+	if err != nil {
+		t.Fatalf("LoadConfig 返回错误: %v", err)
+	}
+	if len(cfg.Files) != 2 || cfg.Files[0] != user || cfg.Files[1] != override {
+		t.Errorf("Files = %v", cfg.Files)
+	}
+	if p := cfg.Profiles["work"]; p.ApiKey != "override-work" || p.Source != override {
+		t.Errorf("work = %+v", p)
+	}
+	if p := cfg.Profiles[DefaultProfile]; p.ApiKey != "user-key" || p.Source != user {
+		t.Errorf("default = %+v", p)
+	}
+	if _, err := LoadConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("-config 指定的文件不存在时应返回错误")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"未知的字段", "apikey_typo: x\n", "未知的字段: apikey_typo"},
+		{"profile 中未知的字段", "profiles:\n  work:\n    key: x\n", "profile work 中未知的字段: key"},
+		{"max_tasks 不是整数", "profiles:\n  work:\n    max_tasks: many\n", "max_tasks 应为非负整数"},
+		{"profiles 不是映射", "profiles: work\n", "profiles 应为映射"},
+		{"缩进错误", "profiles:\n  work:\n    api_key: x\n   personal:\n", "缩进不正确"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			path := filepath.Join(dir, "config.yaml")
+			os.WriteFile(path, []byte(tt.content), 0600)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %v，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveApiKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		flag       string // -profile
+		envKey     string // RUNNINGHUB_API_KEY
+		envProfile string // RUNNINGHUB_PROFILE
+		want       string // 期望的 API Key，为空时期望返回错误
+		source     string // 来源说明或错误信息中应包含的内容
+	}{
+		{"命令行 profile 优先于环境变量", testConfig, "personal", "env-key", "work", "personal-key", "profile personal"},
+		{"命令行 profile 不存在", testConfig, "nobody", "env-key", "", "", "profile 不存在: nobody（可用: default, empty, personal, work）"},
+		{"命令行 profile 没有 api_key", testConfig, "empty", "", "", "", "profile empty 未设置 api_key"},
+		{"环境变量 API Key 优先于 profile 设置", testConfig, "", "env-key", "personal", "env-key", "环境变量 " + EnvApiKey},
+		{"环境变量 profile 优先于配置文件", testConfig, "", "", "personal", "personal-key", "profile personal"},
+		{"环境变量 profile 不存在", testConfig, "", "", "nobody", "", "profile 不存在: nobody"},
+		{"配置文件 profile", testConfig, "", "", "", "work-key", "profile work"},
+		{"default profile", "api_key: default-key\nprofiles:\n  work:\n    api_key: work-key\n", "", "", "", "default-key", "config.yaml"},
+		{"没有任何 API Key", "profiles:\n  work:\n    max_tasks: 1\n", "", "", "", "", "未找到 API Key"},
+		{"空配置文件", "", "", "", "", "", "未找到 API Key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, tt.config)
+			t.Setenv(EnvApiKey, tt.envKey)
+			t.Setenv(EnvProfile, tt.envProfile)
+
+			key, source, err := cfg.ResolveApiKey(tt.flag)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("期望返回错误，得到 %q（%s）", key, source)
+				}
+				if !strings.Contains(err.Error(), tt.source) {
+					t.Errorf("错误 = %q，期望包含 %q", err, tt.source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveApiKey 返回错误: %v", err)
+			}
+			if key != tt.want || !strings.Contains(source, tt.source) {
+				t.Errorf("ResolveApiKey = %q（%s），期望 %q（包含 %q）", key, source, tt.want, tt.source)
+			}
+		})
+	}
+}
+
+// TestResolveApiKeyMissing 没有 API Key 时返回 ErrNoApiKey，并列出可以设置的位置
+func TestResolveApiKeyMissing(t *testing.T) {
+	cfg := loadTestConfig(t, "# 没有 api_key\n")
+	_, _, err := cfg.ResolveApiKey("")
+	if !errors.Is(err, ErrNoApiKey) {
+		t.Fatalf("错误 = %v，期望 ErrNoApiKey", err)
+	}
+	for _, want := range []string{EnvApiKey, ProjectConfigFile, "-config", cfg.Files[0]} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误信息中缺少 %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestResolvePool(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		flag       string
+		envKey     string
+		envProfile string
+		want       string // 逗号分隔的 profile 名称，为空表示不使用 Key 池
+		err        string
+	}{
+		{"配置文件 pool", testConfig, "", "", "", "work,personal", ""},
+		{"命令行多个 profile", testConfig, "personal, work,personal", "", "", "personal,work", ""},
+		{"命令行单个 profile 不使用 Key 池", testConfig, "work", "", "", "", ""},
+		{"环境变量 API Key 不使用 Key 池", testConfig, "", "env-key", "", "", ""},
+		{"环境变量 profile 不使用 Key 池", testConfig, "", "", "work", "", ""},
+		{"pool 只有一个 profile", "pool: [work]\nprofiles:\n  work:\n    api_key: w\n", "", "", "", "", ""},
+		{"pool 中的 profile 没有 api_key", testConfig, "work,empty", "", "", "", "profile empty 未设置 api_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, tt.config)
+			t.Setenv(EnvApiKey, tt.envKey)
+			t.Setenv(EnvProfile, tt.envProfile)
+
+			profiles, err := cfg.ResolvePool(tt.flag)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("错误 = %v，期望包含 %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolvePool 返回错误: %v", err)
+			}
+			var names []string
+			for _, p := range profiles {
+				names = append(names, p.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("ResolvePool = %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// DispositionMode 输入文件的处理方式
+type DispositionMode string
+
+const (
+	DispositionMove   DispositionMode = "move"   // 移动到完成/失败目录
+	DispositionCopy   DispositionMode = "copy"   // 复制到完成/失败目录，保留原文件
+	DispositionMark   DispositionMode = "mark"   // 原地保留，写入 .done/.failed 标记文件
+	DispositionDelete DispositionMode = "delete" // 成功后删除原文件
+	DispositionNone   DispositionMode = "none"   // 不做任何处理
+)
+
+// 标记文件后缀
+const (
+	DoneMarkerSuffix   = ".done"
+	FailedMarkerSuffix = ".failed"
+)
+
+// Disposition 批量任务结束后输入文件的处理策略
+type Disposition struct {
+	Mode      DispositionMode // 处理方式
+	DoneDir   string          // 成功的输入文件目标目录（move/copy 使用）
+	FailedDir string          // 失败的输入文件目标目录，为空时失败的文件保留在原位置
+}
+
+// DefaultDisposition 返回默认处理策略：成功的文件移动到 tmp/，失败的文件保留在原位置
+func DefaultDisposition() Disposition {
+	return Disposition{
+		Mode:    DispositionMove,
+		DoneDir: "tmp",
+	}
+}
+
+// ParseDispositionMode 解析处理方式
+func ParseDispositionMode(s string) (DispositionMode, error) {
+	switch mode := DispositionMode(strings.ToLower(s)); mode {
+	case DispositionMove, DispositionCopy, DispositionMark, DispositionDelete, DispositionNone:
+		return mode, nil
+	}
+	return "", fmt.Errorf("不支持的处理方式: %s（支持 move、copy、mark、delete、none）", s)
+}
+
+// Apply 根据任务结果处理输入文件，返回文件的新位置，未产生新文件时返回空字符串
+// 失败的文件在设置了 FailedDir 时移动（copy 模式下复制）到该目录，否则保留在原位置
+func (d Disposition) Apply(input InputFile, succeeded bool) (string, error) {
+	rel := filepath.FromSlash(input.RelPath)
+	if rel == "" {
+		rel = filepath.Base(input.Path)
+	}
+
+	if !succeeded {
+		switch {
+		case d.Mode == DispositionMark:
+			return input.Path + FailedMarkerSuffix, writeMarker(input.Path+FailedMarkerSuffix, input.Path)
+		case d.Mode == DispositionNone || d.FailedDir == "":
+			return "", nil
+		case d.Mode == DispositionCopy:
+			return copyToDir(input.Path, d.FailedDir, rel)
+		default:
+			return moveToDir(input.Path, d.FailedDir, rel)
+		}
+	}
+
+	switch d.Mode {
+	case DispositionMove, "":
+		dir := d.DoneDir
+		if dir == "" {
+			dir = "tmp"
+		}
+		return moveToDir(input.Path, dir, rel)
+	case DispositionCopy:
+		dir := d.DoneDir
+		if dir == "" {
+			dir = "tmp"
+		}
+		return copyToDir(input.Path, dir, rel)
+	case DispositionMark:
+		// 成功后清除之前失败留下的标记
+		os.Remove(input.Path + FailedMarkerSuffix)
+		return input.Path + DoneMarkerSuffix, writeMarker(input.Path+DoneMarkerSuffix, input.Path)
+	case DispositionDelete:
+		if err := os.Remove(input.Path); err != nil {
+			return "", fmt.Errorf("删除文件失败: %v", err)
+		}
+		return "", nil
+	case DispositionNone:
+		return "", nil
+	}
+	return "", fmt.Errorf("不支持的处理方式: %s", d.Mode)
+}
+
+// IsMarkedDone 判断输入文件是否已有完成标记
+func IsMarkedDone(filePath string) bool {
+	_, err := os.Stat(filePath + DoneMarkerSuffix)
+	return err == nil
+}
+
+// writeMarker 写入标记文件，内容为原文件路径
+func writeMarker(markerPath, filePath string) error {
+	if err := os.WriteFile(markerPath, []byte(filePath+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入标记文件失败: %v", err)
+	}
+	return nil
+}
+
+// moveToDir 将文件移动到 dir 下的 rel 位置
+// 跨文件系统无法重命名时回退为复制后删除，其他重命名错误直接返回
+func moveToDir(src, dir, rel string) (string, error) {
+	dst, err := prepareDestination(dir, rel)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(src, dst)
+	if err == nil {
+		return dst, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return "", fmt.Errorf("移动文件失败: %v", err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		return "", err
+	}
+	if err := os.Remove(src); err != nil {
+		return dst, fmt.Errorf("已复制到 %s，但删除原文件失败: %v", dst, err)
+	}
+	return dst, nil
+}
+
+// copyToDir 将文件复制到 dir 下的 rel 位置
+func copyToDir(src, dir, rel string) (string, error) {
+	dst, err := prepareDestination(dir, rel)
+	if err != nil {
+		return "", err
+	}
+	if err := copyFile(src, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// prepareDestination 创建目标目录，并在目标文件已存在时生成不冲突的文件名
+func prepareDestination(dir, rel string) (string, error) {
+	dst := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %v", err)
+	}
+	return uniquePath(dst), nil
+}
+
+// uniquePath 路径已存在时在扩展名前追加 _1、_2 等序号
+func uniquePath(p string) string {
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return p
+	}
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// copyFile 复制文件内容和权限，失败时删除不完整的目标文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("复制文件失败: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("复制文件失败: %v", err)
+	}
+	return nil
+}
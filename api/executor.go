@@ -1,21 +1,65 @@
 package api
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
 // WorkflowExecutor 工作流执行器
 type WorkflowExecutor struct {
-	manager *WorkflowManager
+	manager   *WorkflowManager
+	selector  *OutputSelector // 本次运行的输出筛选规则，覆盖工作流配置
+	retry     *RetryPolicy    // 本次运行的重试规则，覆盖工作流配置
+	timeout   *TaskTimeout    // 本次运行的超时时间，覆盖工作流配置
+	seed      *SeedPolicy     // 本次运行的种子规则，为空时使用工作流配置中的种子
+	notifiers []Notifier      // 任务和批量运行结束时的通知
+	interrupt interruptState  // 中断状态，见 Interrupt
+	seeds     seedFieldCache  // 从工作流 JSON 中找到的随机种子字段
 }
 
 // NewWorkflowExecutor 创建工作流执行器
 func NewWorkflowExecutor(manager *WorkflowManager) *WorkflowExecutor {
 	return &WorkflowExecutor{
-		manager: manager,
+		manager:   manager,
+		interrupt: interruptState{done: make(chan struct{})},
+	}
+}
+
+// SetOutputSelector 设置本次运行的输出筛选规则，覆盖各工作流的配置，nil 表示使用工作流配置
+func (we *WorkflowExecutor) SetOutputSelector(selector *OutputSelector) {
+	we.selector = selector
+}
+
+// OutputSelector 返回工作流生效的输出筛选规则，可能为 nil
+func (we *WorkflowExecutor) OutputSelector(workflowID string) *OutputSelector {
+	if we.selector != nil {
+		return we.selector
+	}
+	if config, exists := we.manager.GetWorkflow(workflowID); exists {
+		return config.Outputs
+	}
+	return nil
+}
+
+// AddNotifier 添加任务和批量运行结束时的通知
+func (we *WorkflowExecutor) AddNotifier(n Notifier) {
+	we.notifiers = append(we.notifiers, n)
+}
+
+// notifyTask 依次调用各通知的 TaskFinished
+func (we *WorkflowExecutor) notifyTask(result *TaskResult) {
+	for _, n := range we.notifiers {
+		n.TaskFinished(result)
+	}
+}
+
+// notifyRun 依次调用各通知的 RunFinished
+func (we *WorkflowExecutor) notifyRun(run *RunRecord) {
+	for _, n := range we.notifiers {
+		n.RunFinished(run)
 	}
 }
 
@@ -41,11 +85,15 @@ func (we *WorkflowExecutor) ExecuteWorkflow(workflowID string) (*TaskCreateRespo
 		})
 	}
 
+	// 按种子规则设置随机种子
+	nodeInfoList = we.applySeed(config, nodeInfoList)
+
 	// 创建任务
 	return CreateAdvancedTask(config.ID, nodeInfoList)
 }
 
 // ExecuteWorkflowWithImage 执行带图片的工作流
+// 文件只设置到与其类型相同的输入节点（如视频只设置到视频节点），其他文件输入节点沿用工作流中的默认值
 func (we *WorkflowExecutor) ExecuteWorkflowWithImage(workflowID string, filePath string) (*TaskCreateResponse, error) {
 	// 获取工作流配置
 	config, exists := we.manager.GetWorkflow(workflowID)
@@ -53,40 +101,18 @@ func (we *WorkflowExecutor) ExecuteWorkflowWithImage(workflowID string, filePath
 		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
 	}
 
-	// 获取文件类型
-	fileType := "image"
-	if strings.HasSuffix(strings.ToLower(filePath), ".mp4") {
-		fileType = "video"
-	}
-
-	// 上传文件
-	uploadResp, err := UploadImage(filePath, fileType)
-	if err != nil {
-		return nil, fmt.Errorf("上传文件失败: %v", err)
+	// 获取文件类型，无法识别的扩展名按图片处理
+	kind := InputKindOf(filePath)
+	if kind == "" {
+		kind = InputImage
 	}
-
-	// 使用工作流配置中的固定参数，但替换图片参数
-	nodeInfoList := make([]NodeInfo, 0, len(config.Params))
-	for _, param := range config.Params {
-		if param.IsImage || param.FieldName == "video" {
-			// 设置图片/视频参数
-			nodeInfoList = append(nodeInfoList, NodeInfo{
-				NodeId:     param.NodeId,
-				FieldName:  param.FieldName,
-				FieldValue: uploadResp.Data.FileName,
-			})
-		} else {
-			// 设置其他参数
-			nodeInfoList = append(nodeInfoList, NodeInfo{
-				NodeId:     param.NodeId,
-				FieldName:  param.FieldName,
-				FieldValue: param.FieldValue,
-			})
-		}
+	if !config.AcceptsInput(kind) {
+		return nil, fmt.Errorf("工作流 %s 没有 %s 类型的输入节点: %s", workflowID, kind, filePath)
 	}
 
-	// 创建任务
-	return CreateAdvancedTask(config.ID, nodeInfoList)
+	return we.ExecuteWorkflowWithInputs(workflowID, TaskInputs{
+		Files: map[InputKind]string{kind: filePath},
+	})
 }
 
 // ExecuteWorkflowWithText 执行带文本的工作流
@@ -121,71 +147,213 @@ func (we *WorkflowExecutor) ExecuteWorkflowWithText(workflowID string, text stri
 		}
 	}
 
+	// 按种子规则设置随机种子
+	nodeInfoList = we.applySeed(config, nodeInfoList)
+
 	// 创建任务
 	return CreateAdvancedTask(config.ID, nodeInfoList)
 }
 
-// ExecuteWorkflowWithVideoAndAudio 执行带视频和音频的工作流
+// ExecuteWorkflowWithVideoAndAudio 执行带视频和音频的工作流，视频和音频分别设置到工作流的视频和音频输入节点
 func (we *WorkflowExecutor) ExecuteWorkflowWithVideoAndAudio(workflowID string, videoPath string, audioPath string) (*TaskCreateResponse, error) {
 	// 获取工作流配置
 	config, exists := we.manager.GetWorkflow(workflowID)
 	if !exists {
 		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
 	}
+	for _, kind := range []InputKind{InputVideo, InputAudio} {
+		if !config.AcceptsInput(kind) {
+			return nil, fmt.Errorf("工作流 %s 没有 %s 类型的输入节点", workflowID, kind)
+		}
+	}
 
-	// 上传视频文件
-	videoResp, err := UploadImage(videoPath, "image")
-	if err != nil {
-		return nil, fmt.Errorf("上传视频文件失败: %v", err)
+	return we.ExecuteWorkflowWithInputs(workflowID, TaskInputs{
+		Files: map[InputKind]string{
+			InputVideo: videoPath,
+			InputAudio: audioPath,
+		},
+	})
+}
+
+// TaskInputs 任务的输入参数
+type TaskInputs struct {
+	Files     map[InputKind]string // 按输入类型指定的本地文件，会上传后设置到对应的输入节点
+	Uploaded  map[InputKind]string // 按输入类型指定的已上传文件的服务器文件名，优先于 Files
+	ApiKey    string               // 上传 Uploaded 中文件使用的 API Key，使用 Key 池时任务固定由该账户创建
+	Text      string               // 文本提示词，替换 text 字段，为空时使用工作流默认值
+	Seed      *int64               // 随机种子，替换随机种子字段（见 WorkflowExecutor.SeedFields），Overrides 中指定的字段除外
+	Overrides []NodeInfo           // 其他节点字段覆盖，文件输入节点的值视为本地文件路径并上传
+	BaseDir   string               // Overrides 中相对文件路径的基准目录，为空时相对于当前目录
+}
+
+// uploadFileType 返回上传接口接受的文件类型，音频按 image 上传
+func uploadFileType(kind InputKind) string {
+	if kind == InputVideo {
+		return "video"
 	}
-	fmt.Printf("[视频] 上传成功: %s\n", videoResp.Data.FileName)
+	return "image"
+}
 
-	// 上传音频文件
-	audioResp, err := UploadImage(audioPath, "image")
-	if err != nil {
-		return nil, fmt.Errorf("上传音频文件失败: %v", err)
+// seedFieldNames 视为随机种子的字段名
+var seedFieldNames = map[string]bool{"seed": true, "noise_seed": true}
+
+// ExecuteWorkflowWithInputs 执行带自定义输入的工作流
+func (we *WorkflowExecutor) ExecuteWorkflowWithInputs(workflowID string, inputs TaskInputs) (*TaskCreateResponse, error) {
+	// 获取工作流配置
+	config, exists := we.manager.GetWorkflow(workflowID)
+	if !exists {
+		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
 	}
-	fmt.Printf("[音频] 上传成功: %s\n", audioResp.Data.FileName)
 
-	// 使用工作流配置中的固定参数，但替换视频和音频参数
-	nodeInfoList := make([]NodeInfo, 0, len(config.Params))
+	if inputs.Seed == nil {
+		// 清单中指定的种子优先于本次运行的种子规则
+		inputs.Seed = we.nextSeed(config)
+	}
+	return withApiKey(inputs.ApiKey, func(apiKey string) (*TaskCreateResponse, error) {
+		nodeInfoList, err := buildNodeInfoList(apiKey, config, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if inputs.Seed != nil {
+			nodeInfoList = we.setSeed(config, nodeInfoList, *inputs.Seed, inputs.Overrides)
+		}
+
+		// 创建任务
+		return createAdvancedTask(apiKey, config.ID, nodeInfoList)
+	})
+}
+
+// buildNodeInfoList 根据工作流配置和输入参数生成节点参数列表，需要时使用 apiKey 上传本地文件
+func buildNodeInfoList(apiKey string, config *WorkflowConfig, inputs TaskInputs) ([]NodeInfo, error) {
+	overrides := make(map[string]interface{}, len(inputs.Overrides))
+	for _, o := range inputs.Overrides {
+		overrides[o.NodeId+"."+o.FieldName] = o.FieldValue
+	}
+
+	// 同一个本地文件只上传一次
+	uploaded := make(map[string]string)
+	upload := func(filePath string, kind InputKind) (string, error) {
+		if name, ok := uploaded[filePath]; ok {
+			return name, nil
+		}
+		uploadResp, err := uploadFile(apiKey, filePath, uploadFileType(kind))
+		if err != nil {
+			return "", fmt.Errorf("上传文件失败: %v", err)
+		}
+		if uploadResp.Code != 0 {
+			return "", fmt.Errorf("上传文件失败: %s, code: %d, msg: %s", filePath, uploadResp.Code, uploadResp.Msg)
+		}
+		uploaded[filePath] = uploadResp.Data.FileName
+		return uploadResp.Data.FileName, nil
+	}
+
+	nodeInfoList := make([]NodeInfo, 0, len(config.Params)+len(inputs.Overrides))
+	applied := make(map[string]bool)
 	for _, param := range config.Params {
-		if param.IsImage {
-			// 根据节点ID设置不同的文件
-			if param.NodeId == "2" {
-				// 设置视频参数
-				nodeInfoList = append(nodeInfoList, NodeInfo{
-					NodeId:     param.NodeId,
-					FieldName:  param.FieldName,
-					FieldValue: videoResp.Data.FileName,
-				})
-				fmt.Printf("[视频] 设置到节点 %s: %s\n", param.NodeId, videoResp.Data.FileName)
-			} else if param.NodeId == "1" {
-				// 设置音频参数
-				nodeInfoList = append(nodeInfoList, NodeInfo{
-					NodeId:     param.NodeId,
-					FieldName:  param.FieldName,
-					FieldValue: audioResp.Data.FileName,
-				})
-				fmt.Printf("[音频] 设置到节点 %s: %s\n", param.NodeId, audioResp.Data.FileName)
+		key := param.NodeId + "." + param.FieldName
+		value := param.FieldValue
+		if kind := param.InputKind(); kind != "" {
+			filePath := inputs.Files[kind]
+			name := inputs.Uploaded[kind]
+			if v, ok := overrides[key]; ok {
+				filePath, name = fmt.Sprint(v), ""
+				if inputs.BaseDir != "" && filePath != "" && !filepath.IsAbs(filePath) {
+					filePath = filepath.Join(inputs.BaseDir, filePath)
+				}
 			}
-		} else {
-			// 设置其他参数
-			nodeInfoList = append(nodeInfoList, NodeInfo{
-				NodeId:     param.NodeId,
-				FieldName:  param.FieldName,
-				FieldValue: param.FieldValue,
-			})
+			if name == "" && filePath == "" {
+				// 没有提供文件的输入节点不传，沿用工作流中的默认值
+				continue
+			}
+			if name == "" {
+				var err error
+				if name, err = upload(filePath, kind); err != nil {
+					return nil, err
+				}
+			}
+			value = name
+		} else if v, ok := overrides[key]; ok {
+			value = v
+		} else if param.FieldName == "text" && inputs.Text != "" {
+			value = inputs.Text
 		}
+		applied[key] = true
+		nodeInfoList = append(nodeInfoList, NodeInfo{
+			NodeId:     param.NodeId,
+			FieldName:  param.FieldName,
+			FieldValue: value,
+		})
 	}
 
-	// 创建任务
-	return CreateAdvancedTask(config.ID, nodeInfoList)
+	// 工作流配置中没有的字段直接追加
+	for _, o := range inputs.Overrides {
+		if key := o.NodeId + "." + o.FieldName; !applied[key] {
+			applied[key] = true
+			nodeInfoList = append(nodeInfoList, o)
+		}
+	}
+	return nodeInfoList, nil
 }
 
-// MonitorTask 监控任务状态
+// ErrTaskFailed 任务在服务器上执行失败
+var ErrTaskFailed = errors.New("任务执行失败")
+
+// 任务失败原因，用于区分需要用户处理的错误
+const (
+	ReasonAuth   = "auth"   // API Key 无效或无权限
+	ReasonBudget = "budget" // 账户金币不足
+)
+
+// 接口错误信息中表示认证失败和金币不足的关键字（不区分大小写）
+var (
+	authErrorKeywords   = []string{"APIKEY_INVALID", "APIKEY_USER_NOT_FOUND", "APIKEY_UNAUTHORIZED", "TOKEN_INVALID", "UNAUTHORIZED"}
+	budgetErrorKeywords = []string{"INSUFFICIENT", "BALANCE", "NOT_ENOUGH_COINS", "余额不足", "金币不足"}
+)
+
+// FailureReason 根据错误判断失败原因，返回 ReasonAuth、ReasonBudget 或空字符串
+func FailureReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrNoCoins) {
+		return ReasonBudget
+	}
+	msg := strings.ToUpper(err.Error())
+	for _, keyword := range budgetErrorKeywords {
+		if strings.Contains(msg, keyword) {
+			return ReasonBudget
+		}
+	}
+	// APIKEY_INVALID_NODE_INFO 表示节点参数错误，不是认证失败
+	msg = strings.ReplaceAll(msg, "APIKEY_INVALID_NODE_INFO", "")
+	for _, keyword := range authErrorKeywords {
+		if strings.Contains(msg, keyword) {
+			return ReasonAuth
+		}
+	}
+	return ""
+}
+
+// MonitorTask 监控任务状态，使用 SetTaskTimeout 设置的超时时间
+// 任务最终状态为 FAILED 时返回包装了 ErrTaskFailed 的错误
+// 执行器被中断时按中断策略返回包装了 ErrTaskCanceled 或 ErrTaskDetached 的错误
 func (we *WorkflowExecutor) MonitorTask(taskID string, onSuccess func(*TaskOutputResponse)) error {
+	return we.monitorTask(taskID, we.timeout, nil, onSuccess)
+}
+
+// MonitorTaskWithTimeout 同 MonitorTask，排队或执行超过 timeout 时取消任务并返回包装了 ErrTaskTimeout 的错误
+// timeout 为 nil 时不限制时间，通常传入 TaskTimeout(workflowID)
+func (we *WorkflowExecutor) MonitorTaskWithTimeout(taskID string, timeout *TaskTimeout, onSuccess func(*TaskOutputResponse)) error {
+	return we.monitorTask(taskID, timeout, nil, onSuccess)
+}
+
+// monitorTask 同 MonitorTaskWithTimeout，服务器返回的状态变化时调用 onStatus（可为空）
+func (we *WorkflowExecutor) monitorTask(taskID string, timeout *TaskTimeout, onStatus func(status string), onSuccess func(*TaskOutputResponse)) error {
+	defer finishTaskKey(taskID)
+	interrupted := we.interrupted()
+	clock := newTaskClock(timeout)
 	start := time.Now()
+	lastStatus := ""
 	for {
 		statusResp, err := QueryTaskStatus(taskID)
 		if err != nil {
@@ -193,10 +361,18 @@ func (we *WorkflowExecutor) MonitorTask(taskID string, onSuccess func(*TaskOutpu
 		}
 
 		elapsed := int(time.Since(start).Seconds())
-		log.Printf("任务状态: %s (已等待 %d 秒)\n", statusResp.Data, elapsed)
+		if statusResp.Data != lastStatus {
+			logInfo("任务状态", "taskId", taskID, "status", statusResp.Data, "elapsed", elapsed)
+			lastStatus = statusResp.Data
+			if onStatus != nil {
+				onStatus(lastStatus)
+			}
+		} else {
+			logDebug("任务状态", "taskId", taskID, "status", statusResp.Data, "elapsed", elapsed)
+		}
 		if statusResp.Data == "SUCCESS" || statusResp.Data == "FAILED" {
 			totalElapsed := int(time.Since(start).Seconds())
-			log.Printf("任务结束，最终状态: %s，总耗时: %d 秒\n", statusResp.Data, totalElapsed)
+			logInfo("任务结束", "taskId", taskID, "status", statusResp.Data, "elapsed", totalElapsed)
 			if statusResp.Data == "SUCCESS" && onSuccess != nil {
 				outputResp, err := QueryTaskOutputs(taskID)
 				if err != nil {
@@ -204,10 +380,24 @@ func (we *WorkflowExecutor) MonitorTask(taskID string, onSuccess func(*TaskOutpu
 				}
 				onSuccess(outputResp)
 			}
+			if statusResp.Data == "FAILED" {
+				return fmt.Errorf("%w: %s", ErrTaskFailed, taskID)
+			}
 			break
 		}
+		if reason, timedOut := clock.check(statusResp.Data); timedOut {
+			return cancelTimedOut(taskID, reason)
+		}
 
-		time.Sleep(2 * time.Second)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-interrupted:
+			if err := we.stopMonitoring(taskID); err != nil {
+				return err
+			}
+			// 等待策略下继续轮询直到任务结束
+			interrupted = nil
+		}
 	}
 	return nil
 }
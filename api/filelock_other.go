@@ -0,0 +1,8 @@
+//go:build !unix
+
+package api
+
+// lockFile 当前平台不支持 flock，不加锁，只依靠进程内的互斥
+func lockFile(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hookOutputLimit 任务记录中保留的钩子输出长度（末尾部分）
+const hookOutputLimit = 2048
+
+// HookResult 一次钩子命令的执行结果，记录在任务结果或运行记录中
+type HookResult struct {
+	Event    string        `json:"event"`            // 触发的事件: task.succeeded、task.failed、run.finished
+	Command  string        `json:"command"`          // 执行的命令
+	ExitCode int           `json:"exitCode"`         // 退出码，无法启动或超时时为 -1
+	Error    string        `json:"error,omitempty"`  // 启动失败、超时或非零退出的说明
+	Output   string        `json:"output,omitempty"` // 标准输出和标准错误的末尾部分
+	Duration time.Duration `json:"duration"`
+}
+
+// Hooks 任务和批量运行结束时执行的本地命令，通过 sh -c（Windows 上为 cmd /C）执行
+//
+// 任务钩子的环境变量:
+//   - RH_STATUS: 任务状态 SUCCESS、FAILED 或 ERROR
+//   - RH_TASK_ID、RH_WORKFLOW_ID、RH_LABEL、RH_ERROR
+//   - RH_INPUT: 本地输入文件，多个时换行分隔
+//   - RH_OUTPUT_PATHS: 已保存的输出文件，换行分隔；RH_OUTPUT_PATH 为第一个输出文件
+//
+// 批量运行钩子的环境变量:
+//   - RH_RUN_ID、RH_RUN_KIND、RH_WORKFLOW_ID、RH_OUTPUT_DIR、RH_RUN_RECORD
+//   - RH_TOTAL、RH_SUCCEEDED、RH_FAILED
+type Hooks struct {
+	OnSuccess   string        // 任务成功时执行
+	OnFailure   string        // 任务失败时执行
+	OnBatchDone string        // 批量运行结束时执行
+	Timeout     time.Duration // 单个命令的超时时间，0 表示不限制
+}
+
+// TaskFinished 按任务状态执行钩子，结果追加到 result.Hooks
+func (h *Hooks) TaskFinished(result *TaskResult) {
+	command := h.OnFailure
+	if result.Succeeded() {
+		command = h.OnSuccess
+	}
+	if command == "" {
+		return
+	}
+	env := []string{
+		"RH_STATUS=" + result.Status,
+		"RH_TASK_ID=" + result.TaskID,
+		"RH_WORKFLOW_ID=" + result.WorkflowID,
+		"RH_LABEL=" + result.Label,
+		"RH_ERROR=" + result.Error,
+		"RH_INPUT=" + strings.Join(result.Inputs, "\n"),
+		"RH_OUTPUT_PATHS=" + strings.Join(result.Files, "\n"),
+	}
+	if len(result.Files) > 0 {
+		env = append(env, "RH_OUTPUT_PATH="+result.Files[0])
+	}
+	hr := h.run(taskEvent(result), command, env)
+	result.Hooks = append(result.Hooks, hr)
+}
+
+// RunFinished 执行批量运行结束钩子，结果追加到 run.Hooks
+func (h *Hooks) RunFinished(run *RunRecord) {
+	if h.OnBatchDone == "" {
+		return
+	}
+	stats := run.Stats()
+	env := []string{
+		"RH_RUN_ID=" + run.ID,
+		"RH_RUN_KIND=" + run.Kind,
+		"RH_WORKFLOW_ID=" + run.WorkflowID,
+		"RH_OUTPUT_DIR=" + run.OutputDir,
+		"RH_RUN_RECORD=" + run.Path(),
+		"RH_TOTAL=" + strconv.Itoa(stats.Total),
+		"RH_SUCCEEDED=" + strconv.Itoa(stats.Succeeded),
+		"RH_FAILED=" + strconv.Itoa(stats.Failed),
+	}
+	hr := h.run(EventRunFinished, h.OnBatchDone, env)
+	run.Hooks = append(run.Hooks, hr)
+}
+
+// run 执行命令并记录结果，命令失败只记录日志，不影响任务状态
+func (h *Hooks) run(event, command string, env []string) HookResult {
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// 超时后子进程可能仍占用输出管道，最多再等待 1 秒
+	cmd.WaitDelay = time.Second
+
+	logInfo("[钩子] 执行命令", "event", event, "command", command)
+	start := time.Now()
+	err := cmd.Run()
+	result := HookResult{
+		Event:    event,
+		Command:  command,
+		Output:   tailString(output.String(), hookOutputLimit),
+		Duration: time.Since(start),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("命令执行超时（%s）", h.Timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = err.Error()
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	if result.Error != "" {
+		logWarn("[钩子] 命令执行失败", "event", event, "command", command, "exitCode", result.ExitCode, "error", result.Error, "output", result.Output)
+	} else {
+		logDebug("[钩子] 命令执行完成", "event", event, "command", command, "output", result.Output)
+	}
+	return result
+}
+
+// tailString 返回字符串末尾最多 limit 个字节，按 UTF-8 字符边界截断
+func tailString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	s = s[len(s)-limit:]
+	for i := 0; i < len(s) && i < 4; i++ {
+		if s[i]&0xC0 != 0x80 {
+			return s[i:]
+		}
+	}
+	return s
+}
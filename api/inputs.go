@@ -0,0 +1,240 @@
+package api
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InputKind 输入文件类型
+type InputKind string
+
+const (
+	InputImage InputKind = "image" // 图片
+	InputVideo InputKind = "video" // 视频
+	InputAudio InputKind = "audio" // 音频
+)
+
+// InputExtensions 每种输入类型支持的扩展名
+var InputExtensions = map[InputKind][]string{
+	InputImage: {".png", ".jpg", ".jpeg", ".webp", ".bmp"},
+	InputVideo: {".mp4", ".mov", ".webm", ".mkv", ".avi"},
+	InputAudio: {".mp3", ".wav", ".flac", ".m4a", ".ogg"},
+}
+
+// 排序方式
+const (
+	SortByName  = "name"
+	SortByMtime = "mtime"
+	SortBySize  = "size"
+)
+
+// InputOptions 输入文件发现选项
+type InputOptions struct {
+	Dir       string      // 输入目录，默认 inputs
+	Recursive bool        // 是否递归子目录
+	Include   []string    // 包含的 glob 模式，为空表示全部包含
+	Exclude   []string    // 排除的 glob 模式
+	Kinds     []InputKind // 接受的输入类型，为空时根据工作流推断
+	SortBy    string      // 排序方式: name, mtime, size
+	Reverse   bool        // 是否倒序
+	Limit     int         // 最多处理的文件数，0 表示不限制
+	SkipDone  bool        // 跳过已有 .done 标记的文件
+}
+
+// InputFile 发现的输入文件
+type InputFile struct {
+	Path    string    // 文件路径
+	RelPath string    // 相对输入目录的路径，使用 / 分隔
+	Kind    InputKind // 文件类型
+	Size    int64     // 文件大小
+	ModTime time.Time // 修改时间
+}
+
+// RelDir 返回文件相对输入目录的子目录，位于顶层时返回空字符串
+func (f InputFile) RelDir() string {
+	dir := path.Dir(f.RelPath)
+	if dir == "." {
+		return ""
+	}
+	return filepath.FromSlash(dir)
+}
+
+// BaseName 返回不含扩展名的文件名
+func (f InputFile) BaseName() string {
+	base := filepath.Base(f.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// DefaultInputOptions 返回默认的输入发现选项
+func DefaultInputOptions() InputOptions {
+	return InputOptions{
+		Dir:    "inputs",
+		SortBy: SortByName,
+	}
+}
+
+// InputKindOf 根据扩展名判断文件类型，无法识别时返回空字符串
+func InputKindOf(filePath string) InputKind {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	for kind, exts := range InputExtensions {
+		for _, e := range exts {
+			if e == ext {
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+// ParseInputKinds 解析逗号分隔的输入类型列表，如 "image,video"
+func ParseInputKinds(s string) ([]InputKind, error) {
+	var kinds []InputKind
+	for _, part := range splitList(s) {
+		kind := InputKind(strings.ToLower(part))
+		if _, ok := InputExtensions[kind]; !ok {
+			return nil, fmt.Errorf("不支持的输入类型: %s", part)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// DiscoverInputs 按选项发现输入文件
+func DiscoverInputs(opts InputOptions) ([]InputFile, error) {
+	if opts.Dir == "" {
+		opts.Dir = "inputs"
+	}
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的匹配模式 %q: %v", pattern, err)
+		}
+	}
+
+	accepted := make(map[InputKind]bool)
+	for _, kind := range opts.Kinds {
+		accepted[kind] = true
+	}
+
+	var files []InputFile
+	err := filepath.WalkDir(opts.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != opts.Dir && !opts.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		kind := InputKindOf(p)
+		if kind == "" || (len(accepted) > 0 && !accepted[kind]) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(opts.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+		if matchAny(opts.Exclude, rel) {
+			return nil
+		}
+		if opts.SkipDone && IsMarkedDone(p) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, InputFile{
+			Path:    p,
+			RelPath: rel,
+			Kind:    kind,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取输入目录失败: %v", err)
+	}
+
+	if err := sortInputs(files, opts.SortBy, opts.Reverse); err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(files) > opts.Limit {
+		files = files[:opts.Limit]
+	}
+	return files, nil
+}
+
+// matchAny 判断相对路径是否匹配任一模式
+// 不含 / 的模式只匹配文件名，含 / 的模式匹配完整相对路径
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortInputs 按指定方式排序输入文件，相同时按路径排序保证顺序稳定
+func sortInputs(files []InputFile, sortBy string, reverse bool) error {
+	var less func(a, b InputFile) bool
+	switch sortBy {
+	case "", SortByName:
+		less = func(a, b InputFile) bool { return a.RelPath < b.RelPath }
+	case SortByMtime:
+		less = func(a, b InputFile) bool {
+			if a.ModTime.Equal(b.ModTime) {
+				return a.RelPath < b.RelPath
+			}
+			return a.ModTime.Before(b.ModTime)
+		}
+	case SortBySize:
+		less = func(a, b InputFile) bool {
+			if a.Size == b.Size {
+				return a.RelPath < b.RelPath
+			}
+			return a.Size < b.Size
+		}
+	default:
+		return fmt.Errorf("不支持的排序方式: %s（支持 name、mtime、size）", sortBy)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if reverse {
+			return less(files[j], files[i])
+		}
+		return less(files[i], files[j])
+	})
+	return nil
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// InterruptPolicy 运行被中断时对执行中任务的处理方式
+type InterruptPolicy string
+
+const (
+	InterruptDetach InterruptPolicy = "detach" // 停止等待，任务在服务器上继续执行，任务ID保存在运行记录中，之后可用 ResumeRun 恢复
+	InterruptCancel InterruptPolicy = "cancel" // 调用 CancelTask 取消执行中的任务
+	InterruptWait   InterruptPolicy = "wait"   // 等待执行中的任务结束
+)
+
+// ParseInterruptPolicy 解析中断策略
+func ParseInterruptPolicy(s string) (InterruptPolicy, error) {
+	switch policy := InterruptPolicy(s); policy {
+	case InterruptDetach, InterruptCancel, InterruptWait:
+		return policy, nil
+	}
+	return "", fmt.Errorf("无效的中断策略: %s（可选 detach, cancel, wait）", s)
+}
+
+// TaskStatusDetached 运行被中断时停止等待的任务，任务仍在服务器上执行
+const TaskStatusDetached = "DETACHED"
+
+var (
+	// ErrTaskCanceled 任务因运行被中断而取消
+	ErrTaskCanceled = errors.New("任务已取消")
+	// ErrTaskDetached 运行被中断，已停止等待任务，任务仍在服务器上执行
+	ErrTaskDetached = errors.New("已停止等待任务，任务仍在服务器上执行")
+)
+
+// interruptState 执行器的中断状态
+type interruptState struct {
+	once   sync.Once
+	done   chan struct{} // 中断后关闭
+	policy InterruptPolicy
+}
+
+// Interrupt 中断执行器上的运行：不再提交新任务，执行中的任务按 policy 处理
+// 可以从其他 goroutine（如信号处理）调用，只有第一次调用生效
+func (we *WorkflowExecutor) Interrupt(policy InterruptPolicy) {
+	we.interrupt.once.Do(func() {
+		we.interrupt.policy = policy
+		close(we.interrupt.done)
+	})
+}
+
+// Interrupted 判断执行器是否已被中断
+func (we *WorkflowExecutor) Interrupted() bool {
+	select {
+	case <-we.interrupt.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// interrupted 返回中断后关闭的通道
+func (we *WorkflowExecutor) interrupted() <-chan struct{} {
+	return we.interrupt.done
+}
+
+// stopMonitoring 运行被中断时按策略处理正在等待的任务，返回 nil 表示继续等待
+func (we *WorkflowExecutor) stopMonitoring(taskID string) error {
+	switch we.interrupt.policy {
+	case InterruptCancel:
+		resp, err := CancelTask(taskID)
+		if err == nil && resp.Code != 0 {
+			err = fmt.Errorf("code: %d, msg: %s", resp.Code, resp.Msg)
+		}
+		if err != nil {
+			logError("取消任务失败", "taskId", taskID, "error", err)
+			return fmt.Errorf("%w: %s（取消失败: %v）", ErrTaskDetached, taskID, err)
+		}
+		logInfo("任务已取消", "taskId", taskID)
+		return fmt.Errorf("%w: %s", ErrTaskCanceled, taskID)
+	case InterruptDetach:
+		logWarn("已停止等待任务，任务仍在服务器上执行", "taskId", taskID)
+		return fmt.Errorf("%w: %s", ErrTaskDetached, taskID)
+	}
+	return nil
+}
+
+// Interrupted 判断任务是否因运行被中断而未完成（已取消、未提交或已停止等待）
+func (r *TaskResult) Interrupted() bool {
+	switch r.Status {
+	case TaskStatusCanceled, TaskStatusSkipped, TaskStatusDetached:
+		return true
+	}
+	return false
+}
+
+// ResumeRun 恢复运行记录中停止等待（DETACHED）的任务：继续等待任务结束，把结果保存到运行的输出目录，
+// 然后更新并保存运行记录。流水线不会继续执行恢复的任务的后续阶段
+func ResumeRun(run *RunRecord, concurrency int, executor *WorkflowExecutor) {
+	var indexes []int
+	for i, task := range run.Tasks {
+		if task != nil && task.Status == TaskStatusDetached {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		logInfo("[恢复] 运行记录中没有需要恢复的任务", "id", run.ID)
+		return
+	}
+
+	logInfo("[恢复] 继续等待任务", "id", run.ID, "count", len(indexes))
+	executor.notifyRunStarted(run, len(indexes))
+	runConcurrent(concurrency, len(indexes), func(i int) {
+		previous := *run.Tasks[indexes[i]]
+		baseName := ""
+		if len(previous.Inputs) > 0 {
+			base := filepath.Base(previous.Inputs[0])
+			baseName = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		run.Tasks[indexes[i]] = runTask(taskJob{
+			tag:       "[恢复]",
+			label:     previous.Label,
+			outputDir: run.OutputDir,
+			baseName:  baseName,
+			inputs:    previous.Inputs,
+			create: func() (*TaskCreateResponse, error) {
+				resp := &TaskCreateResponse{WorkflowId: previous.WorkflowID, NodeInfoList: previous.NodeInfoList, Profile: previous.Profile}
+				resp.Data.TaskId = previous.TaskID
+				restoreTaskKey(previous.TaskID, previous.Profile)
+				return resp, nil
+			},
+		}, executor)
+	})
+	run.finish(executor)
+	logInfo("[恢复] 恢复完成", "id", run.ID)
+}
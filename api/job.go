@@ -0,0 +1,184 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 服务模式下的作业状态，结束状态与 TaskResult.Status 一致
+const (
+	JobQueued   = "QUEUED"   // 排队中
+	JobRunning  = "RUNNING"  // 执行中
+	JobCanceled = "CANCELED" // 已取消
+)
+
+// Job 通过 HTTP 接口提交的作业，对应一个任务
+type Job struct {
+	ID         string                 `json:"id"`
+	Status     string                 `json:"status"`           // QUEUED, RUNNING, SUCCESS, FAILED, TIMEOUT, ERROR, CANCELED
+	WorkflowID string                 `json:"workflowId"`       // 工作流ID
+	Request    map[string]interface{} `json:"request"`          // 提交的字段，格式与清单的一行相同
+	Result     *TaskResult            `json:"result,omitempty"` // 任务结果，任务创建后即有任务ID
+	Created    time.Time              `json:"created"`
+	Updated    time.Time              `json:"updated"`
+	Canceling  bool                   `json:"canceling,omitempty"` // 已请求取消，等待服务器结束任务
+}
+
+// Finished 判断作业是否已结束
+func (j *Job) Finished() bool {
+	return j.Status != JobQueued && j.Status != JobRunning
+}
+
+// manifestJob 将提交的字段转换为任务
+func (j *Job) manifestJob() (ManifestJob, error) {
+	job, err := manifestRow{fields: j.Request}.toJob("", "")
+	if err != nil {
+		return job, err
+	}
+	if job.Name == "" && len(job.inputFiles()) == 0 {
+		job.Name = "job_" + j.ID
+	}
+	return job, nil
+}
+
+// JobStore 作业存储，每个作业保存为目录下的 <ID>.json，服务重启后可以恢复
+type JobStore struct {
+	dir  string
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobStore 打开作业目录并加载已保存的作业
+func NewJobStore(dir string) (*JobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建作业目录失败: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取作业目录失败: %v", err)
+	}
+	store := &JobStore{dir: dir, jobs: make(map[string]*Job)}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取作业失败: %v", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			logWarn("跳过无法解析的作业文件", "file", entry.Name(), "error", err)
+			continue
+		}
+		store.jobs[job.ID] = &job
+	}
+	return store, nil
+}
+
+// Dir 返回作业目录
+func (s *JobStore) Dir() string {
+	return s.dir
+}
+
+// newJobID 生成作业ID
+func newJobID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102150405") + "_" + hex.EncodeToString(b)
+}
+
+// Create 创建排队中的作业并保存
+func (s *JobStore) Create(workflowID string, request map[string]interface{}) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		ID:         newJobID(),
+		Status:     JobQueued,
+		WorkflowID: workflowID,
+		Request:    request,
+		Created:    now,
+		Updated:    now,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.save(job); err != nil {
+		return nil, err
+	}
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+// Get 返回作业的副本
+func (s *JobStore) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// List 按创建时间返回作业副本，status 不为空时只返回该状态的作业
+func (s *JobStore) List(status string) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if status != "" && !strings.EqualFold(job.Status, status) {
+			continue
+		}
+		copied := *job
+		jobs = append(jobs, &copied)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].Created.Equal(jobs[j].Created) {
+			return jobs[i].Created.Before(jobs[j].Created)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// Update 在锁内修改作业并保存，作业不存在时返回错误
+func (s *JobStore) Update(id string, fn func(job *Job)) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("作业不存在: %s", id)
+	}
+	fn(job)
+	job.Updated = time.Now()
+	if err := s.save(job); err != nil {
+		return nil, err
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// save 写入作业文件，调用方需持有锁
+func (s *JobStore) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化作业失败: %v", err)
+	}
+	path := filepath.Join(s.dir, job.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入作业失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入作业失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// keyPoolRefreshInterval 账户状态的刷新间隔
+const keyPoolRefreshInterval = 30 * time.Second
+
+// keyPoolWaitInterval 所有账户都达到任务上限时，重新检查的间隔
+const keyPoolWaitInterval = 5 * time.Second
+
+// ErrNoCoins 所有账户的金币都已用完
+var ErrNoCoins = errors.New("所有账户的金币都已用完")
+
+// ErrKeyNotInPool 指定的 API Key 不属于 Key 池中的任何账户
+var ErrKeyNotInPool = errors.New("API Key 不在 Key 池中")
+
+// poolKey Key 池中的一个账户
+type poolKey struct {
+	profile     *Profile
+	remainCoins float64   // 最近一次查询的剩余金币，未知时为 -1
+	serverTasks int       // 最近一次查询的当前任务数
+	started     int       // 未计入 serverTasks 的本进程新建的任务数
+	finished    int       // 未计入 serverTasks 的本进程结束的任务数
+	checked     time.Time // 最近一次查询时间
+}
+
+// load 估算当前任务数
+func (k *poolKey) load() int {
+	if n := k.serverTasks + k.started - k.finished; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// KeyPool 多个账户的 API Key 池，新任务分配给剩余金币充足且当前任务最少的账户，
+// 并记录每个尚未结束的任务使用的 Key，查询状态、结果和取消任务时使用同一个 Key
+type KeyPool struct {
+	mu    sync.Mutex
+	keys  []*poolKey
+	tasks map[string]*poolKey // 尚未结束的任务ID -> 创建任务的账户
+}
+
+// NewKeyPool 根据 profile 创建 Key 池
+func NewKeyPool(profiles []*Profile) (*KeyPool, error) {
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("Key 池中没有账户")
+	}
+	pool := &KeyPool{tasks: make(map[string]*poolKey)}
+	for _, p := range profiles {
+		if p.ApiKey == "" {
+			return nil, fmt.Errorf("profile %s 未设置 api_key", p.Name)
+		}
+		pool.keys = append(pool.keys, &poolKey{profile: p, remainCoins: -1})
+	}
+	return pool, nil
+}
+
+// keyPool 当前使用的 Key 池，为 nil 时所有请求使用 ApiKey
+var (
+	keyPool   *KeyPool
+	keyPoolMu sync.RWMutex
+)
+
+// SetKeyPool 设置 Key 池，nil 表示只使用 ApiKey
+func SetKeyPool(pool *KeyPool) {
+	keyPoolMu.Lock()
+	keyPool = pool
+	keyPoolMu.Unlock()
+}
+
+// GetKeyPool 返回当前的 Key 池，可能为 nil
+func GetKeyPool() *KeyPool {
+	keyPoolMu.RLock()
+	defer keyPoolMu.RUnlock()
+	return keyPool
+}
+
+// Profiles 返回池中的账户
+func (p *KeyPool) Profiles() []*Profile {
+	profiles := make([]*Profile, len(p.keys))
+	for i, k := range p.keys {
+		profiles[i] = k.profile
+	}
+	return profiles
+}
+
+// refresh 刷新过期的账户状态，调用方不能持有锁
+// 查询期间不持有锁，只扣除查询前已计入的本地任务数，查询期间新建和结束的任务保留到下次查询
+func (p *KeyPool) refresh() {
+	type snapshot struct {
+		key               *poolKey
+		started, finished int
+	}
+	p.mu.Lock()
+	var stale []snapshot
+	for _, k := range p.keys {
+		if time.Since(k.checked) >= keyPoolRefreshInterval {
+			// 先更新查询时间，避免并发的 refresh 重复查询同一账户
+			k.checked = time.Now()
+			stale = append(stale, snapshot{key: k, started: k.started, finished: k.finished})
+		}
+	}
+	p.mu.Unlock()
+
+	for _, snap := range stale {
+		k := snap.key
+		status, err := GetAccountStatus(k.profile.ApiKey)
+		p.mu.Lock()
+		k.checked = time.Now()
+		if err != nil || status.Code != 0 {
+			// 查询失败时保留之前的状态，下次再试
+			if err == nil {
+				err = fmt.Errorf("code: %d, msg: %s", status.Code, status.Msg)
+			}
+			logWarn("[Key池] 查询账户状态失败", "profile", k.profile.Name, "error", err)
+		} else {
+			if coins, err := strconv.ParseFloat(status.Data.RemainCoins, 64); err == nil {
+				k.remainCoins = coins
+			}
+			if tasks, err := strconv.Atoi(status.Data.CurrentTaskCounts); err == nil {
+				k.serverTasks = tasks
+				k.started -= snap.started
+				k.finished -= snap.finished
+			}
+			logDebug("[Key池] 账户状态", "profile", k.profile.Name, "remainCoins", k.remainCoins, "currentTasks", k.serverTasks)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// acquire 为新任务选择账户，pinned 不为空时使用该 Key；所有账户都达到任务上限时等待
+func (p *KeyPool) acquire(pinned string) (*poolKey, error) {
+	for {
+		p.refresh()
+		p.mu.Lock()
+		var best *poolKey
+		hasCoins, found := false, false
+		for _, k := range p.keys {
+			if pinned != "" && k.profile.ApiKey != pinned {
+				continue
+			}
+			found = true
+			if k.remainCoins >= 0 && k.remainCoins < 1 {
+				continue
+			}
+			hasCoins = true
+			if k.profile.MaxTasks > 0 && k.load() >= k.profile.MaxTasks {
+				continue
+			}
+			if best == nil || k.load() < best.load() || (k.load() == best.load() && k.remainCoins > best.remainCoins) {
+				best = k
+			}
+		}
+		if best != nil {
+			best.started++
+			p.mu.Unlock()
+			logDebug("[Key池] 分配账户", "profile", best.profile.Name, "load", best.load())
+			return best, nil
+		}
+		p.mu.Unlock()
+		if !found {
+			return nil, ErrKeyNotInPool
+		}
+		if !hasCoins {
+			return nil, ErrNoCoins
+		}
+		logInfo("[Key池] 所有账户都达到任务上限，等待空闲", "retryIn", keyPoolWaitInterval)
+		time.Sleep(keyPoolWaitInterval)
+	}
+}
+
+// release 任务创建失败时归还名额
+func (p *KeyPool) release(k *poolKey) {
+	p.mu.Lock()
+	k.finished++
+	p.mu.Unlock()
+}
+
+// remember 记录任务使用的账户
+func (p *KeyPool) remember(taskID string, k *poolKey) {
+	p.mu.Lock()
+	p.tasks[taskID] = k
+	p.mu.Unlock()
+}
+
+// finish 任务结束后归还名额并删除任务的记录，同一个任务只归还一次
+func (p *KeyPool) finish(taskID string) {
+	p.mu.Lock()
+	if k, ok := p.tasks[taskID]; ok {
+		delete(p.tasks, taskID)
+		k.finished++
+	}
+	p.mu.Unlock()
+}
+
+// restore 恢复之前记录的任务账户（如服务重启后继续等待的任务），profile 不在池中时返回 false
+func (p *KeyPool) restore(taskID, profile string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.profile.Name == profile {
+			if _, ok := p.tasks[taskID]; !ok {
+				k.started++
+			}
+			p.tasks[taskID] = k
+			return true
+		}
+	}
+	return false
+}
+
+// TaskProfile 返回创建尚未结束的任务的账户，未记录或任务已结束时返回 nil
+func (p *KeyPool) TaskProfile(taskID string) *Profile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.tasks[taskID]; ok {
+		return k.profile
+	}
+	return nil
+}
+
+// taskKeys 通过 RememberTaskKey 记录的任务 API Key，任务ID -> API Key
+var taskKeys sync.Map
+
+// RememberTaskKey 记录创建任务时使用的 API Key（如从任务登记表中得知任务由其他账户创建），
+// 之后查询、取消该任务时使用该 Key
+func RememberTaskKey(taskID, apiKey string) {
+	if apiKey != "" {
+		taskKeys.Store(taskID, apiKey)
+	}
+}
+
+// taskApiKey 返回查询、取消任务时使用的 API Key
+func taskApiKey(taskID string) string {
+	if pool := GetKeyPool(); pool != nil {
+		if profile := pool.TaskProfile(taskID); profile != nil {
+			return profile.ApiKey
+		}
+	}
+	if key, ok := taskKeys.Load(taskID); ok {
+		return key.(string)
+	}
+	return ApiKey
+}
+
+// restoreTaskKey 恢复之前记录的任务账户，之后对该任务的请求使用该账户的 Key
+func restoreTaskKey(taskID, profile string) {
+	if pool := GetKeyPool(); pool != nil && profile != "" {
+		if !pool.restore(taskID, profile) {
+			logWarn("[Key池] 任务的账户不在 Key 池中，使用默认 API Key", "taskId", taskID, "profile", profile)
+		}
+	}
+}
+
+// finishTaskKey 任务结束后归还账户名额，之后该任务的请求不再使用池中的 Key
+func finishTaskKey(taskID string) {
+	if pool := GetKeyPool(); pool != nil {
+		pool.finish(taskID)
+	}
+}
+
+// queryApiKeys 返回与具体任务无关的查询（如获取工作流 JSON）可以使用的 API Key：
+// 使用 Key 池时为池中各账户的 Key（ApiKey 同样属于池中账户时已包含在内），否则为 ApiKey
+func queryApiKeys() []string {
+	pool := GetKeyPool()
+	if pool == nil {
+		return []string{ApiKey}
+	}
+	var keys []string
+	for _, profile := range pool.Profiles() {
+		keys = append(keys, profile.ApiKey)
+	}
+	return keys
+}
+
+// withApiKey 为新任务选择 API Key 并执行 create，pinned 不为空时固定使用该 Key（如文件已用该 Key 上传）
+// 任务创建成功后记录任务使用的 Key，失败时归还名额
+func withApiKey(pinned string, create func(apiKey string) (*TaskCreateResponse, error)) (*TaskCreateResponse, error) {
+	pool := GetKeyPool()
+	if pool == nil {
+		if pinned == "" {
+			return create(ApiKey)
+		}
+		// 之后查询、取消新任务时同样使用该 Key
+		resp, err := create(pinned)
+		if err == nil && resp.Code == 0 && resp.Data.TaskId != "" {
+			RememberTaskKey(resp.Data.TaskId, pinned)
+		}
+		return resp, err
+	}
+
+	k, err := pool.acquire(pinned)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := create(k.profile.ApiKey)
+	if err != nil || resp.Code != 0 || resp.Data.TaskId == "" {
+		pool.release(k)
+		return resp, err
+	}
+	pool.remember(resp.Data.TaskId, k)
+	resp.Profile = k.profile.Name
+	return resp, nil
+}
+
+// acquireUploadKey 为需要先上传、后由多个任务共用的文件选择 API Key，不占用任务名额
+func acquireUploadKey() (string, error) {
+	pool := GetKeyPool()
+	if pool == nil {
+		return ApiKey, nil
+	}
+	k, err := pool.acquire("")
+	if err != nil {
+		return "", err
+	}
+	pool.release(k)
+	return k.profile.ApiKey, nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Logger 日志接口
+// 方法签名与 *slog.Logger 一致，可以直接传入 slog.Default() 或自定义的 slog.Logger
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = NewLogger(os.Stderr, slog.LevelInfo)
+)
+
+// NewLogger 创建输出到 w 的文本日志器，低于 level 的日志会被丢弃
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// SetLogger 设置 api 包使用的日志器
+// 传入 nil 时丢弃所有日志，适合作为库静默使用
+func SetLogger(l Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	loggerMu.Lock()
+	logger = l
+	loggerMu.Unlock()
+}
+
+// GetLogger 获取 api 包当前使用的日志器
+func GetLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+// discardHandler 丢弃所有日志的 slog.Handler
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+func logDebug(msg string, args ...any) { GetLogger().Debug(redactSecrets(msg), redactArgs(args)...) }
+func logInfo(msg string, args ...any)  { GetLogger().Info(redactSecrets(msg), redactArgs(args)...) }
+func logWarn(msg string, args ...any)  { GetLogger().Warn(redactSecrets(msg), redactArgs(args)...) }
+func logError(msg string, args ...any) { GetLogger().Error(redactSecrets(msg), redactArgs(args)...) }
+
+// apiKeyFieldPattern 匹配 JSON 或表单中的 apiKey 字段
+var apiKeyFieldPattern = regexp.MustCompile(`(?i)("api_?key"\s*:\s*")[^"]*(")`)
+
+// redactSecrets 将文本中的 API Key 替换为掩码
+func redactSecrets(s string) string {
+	s = apiKeyFieldPattern.ReplaceAllString(s, `${1}***${2}`)
+	keys := []string{ApiKey}
+	if pool := GetKeyPool(); pool != nil {
+		for _, p := range pool.Profiles() {
+			keys = append(keys, p.ApiKey)
+		}
+	}
+	taskKeys.Range(func(_, key any) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	for _, key := range keys {
+		if len(key) >= 4 {
+			s = strings.ReplaceAll(s, key, maskSecret(key))
+		}
+	}
+	return s
+}
+
+// maskSecret 只保留密钥的前4位
+func maskSecret(key string) string {
+	if len(key) <= 4 {
+		return "***"
+	}
+	return key[:4] + "***"
+}
+
+// redactArgs 对日志参数中的字符串值做脱敏
+func redactArgs(args []any) []any {
+	if len(args) == 0 {
+		return args
+	}
+	out := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			out[i] = redactSecrets(v)
+		case []byte:
+			out[i] = redactSecrets(string(v))
+		case error:
+			out[i] = redactSecrets(v.Error())
+		case slog.Attr:
+			if v.Value.Kind() == slog.KindString {
+				v.Value = slog.StringValue(redactSecrets(v.Value.String()))
+			}
+			out[i] = v
+		case fmt.Stringer:
+			out[i] = redactSecrets(v.String())
+		default:
+			out[i] = arg
+		}
+	}
+	return out
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ManifestJob 清单中的一个任务
+type ManifestJob struct {
+	Line       int        // 在清单文件中的行号
+	WorkflowID string     // 工作流ID
+	Name       string     // 输出文件名前缀，为空时使用第一个输入文件名
+	Inputs     TaskInputs // 任务输入
+}
+
+// Label 返回用于日志的任务标识
+func (j ManifestJob) Label() string {
+	return fmt.Sprintf("第 %d 行(%s)", j.Line, j.BaseName())
+}
+
+// BaseName 返回输出文件名前缀
+func (j ManifestJob) BaseName() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	for _, kind := range []InputKind{InputImage, InputVideo, InputAudio} {
+		if p := j.Inputs.Files[kind]; p != "" {
+			base := filepath.Base(p)
+			return strings.TrimSuffix(base, filepath.Ext(base))
+		}
+	}
+	return fmt.Sprintf("row_%d", j.Line)
+}
+
+// LoadManifest 读取 CSV 或 JSONL 格式的任务清单
+//
+// 每行可以包含以下字段:
+//   - workflow: 工作流ID，为空时使用 defaultWorkflowID
+//   - name: 输出文件名前缀
+//   - image / video / audio: 本地输入文件路径，相对路径以清单文件所在目录为基准
+//   - text (或 prompt): 文本提示词
+//   - seed: 随机种子
+//   - <节点ID>.<字段名>: 其他节点字段覆盖，如 40.text、3.steps
+//
+// CSV 第一行为表头，以 # 开头的行视为注释；文件扩展名为 .jsonl 或 .ndjson 时按 JSON Lines 解析
+func LoadManifest(manifestPath string, defaultWorkflowID string) ([]ManifestJob, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %v", err)
+	}
+
+	var rows []manifestRow
+	switch strings.ToLower(filepath.Ext(manifestPath)) {
+	case ".jsonl", ".ndjson":
+		rows, err = parseJSONLManifest(data)
+	case ".csv":
+		rows, err = parseCSVManifest(data)
+	default:
+		return nil, fmt.Errorf("不支持的清单格式: %s（支持 .csv、.jsonl）", manifestPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	baseDir := filepath.Dir(manifestPath)
+	jobs := make([]ManifestJob, 0, len(rows))
+	for _, row := range rows {
+		job, err := row.toJob(baseDir, defaultWorkflowID)
+		if err != nil {
+			return nil, fmt.Errorf("清单第 %d 行: %v", row.line, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// manifestRow 清单中的原始一行
+type manifestRow struct {
+	line   int
+	fields map[string]interface{}
+}
+
+// parseCSVManifest 解析带表头的 CSV 清单
+func parseCSVManifest(data []byte) ([]manifestRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析清单表头失败: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	var rows []manifestRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析清单失败: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		fields := make(map[string]interface{}, len(header))
+		for i, value := range record {
+			if i < len(header) && strings.TrimSpace(value) != "" {
+				fields[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		if len(fields) > 0 {
+			rows = append(rows, manifestRow{line: line, fields: fields})
+		}
+	}
+	return rows, nil
+}
+
+// parseJSONLManifest 解析每行一个 JSON 对象的清单
+func parseJSONLManifest(data []byte) ([]manifestRow, error) {
+	var rows []manifestRow
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		var fields map[string]interface{}
+		if err := decoder.Decode(&fields); err != nil {
+			return nil, fmt.Errorf("清单第 %d 行: 解析 JSON 失败: %v", line, err)
+		}
+		rows = append(rows, manifestRow{line: line, fields: fields})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取清单失败: %v", err)
+	}
+	return rows, nil
+}
+
+// toJob 将原始行转换为任务
+func (r manifestRow) toJob(baseDir, defaultWorkflowID string) (ManifestJob, error) {
+	job := ManifestJob{
+		Line:       r.line,
+		WorkflowID: defaultWorkflowID,
+		Inputs:     TaskInputs{Files: make(map[InputKind]string), BaseDir: baseDir},
+	}
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+
+	for key, value := range r.fields {
+		str := strings.TrimSpace(fmt.Sprint(value))
+		switch strings.ToLower(key) {
+		case "workflow", "workflowid", "workflow_id":
+			if str != "" {
+				job.WorkflowID = str
+			}
+		case "name":
+			job.Name = str
+		case "image", "video", "audio":
+			if str != "" {
+				job.Inputs.Files[InputKind(strings.ToLower(key))] = resolve(str)
+			}
+		case "text", "prompt":
+			job.Inputs.Text = str
+		case "seed":
+			if str == "" {
+				continue
+			}
+			seed, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return job, fmt.Errorf("无效的 seed: %s", str)
+			}
+			job.Inputs.Seed = &seed
+		default:
+			nodeID, fieldName, ok := strings.Cut(key, ".")
+			if !ok || nodeID == "" || fieldName == "" {
+				return job, fmt.Errorf("无法识别的字段: %s（节点字段请使用 <节点ID>.<字段名>）", key)
+			}
+			job.Inputs.Overrides = append(job.Inputs.Overrides, NodeInfo{
+				NodeId:     nodeID,
+				FieldName:  fieldName,
+				FieldValue: value,
+			})
+		}
+	}
+
+	if job.WorkflowID == "" {
+		return job, fmt.Errorf("未指定工作流ID")
+	}
+	for kind, p := range job.Inputs.Files {
+		if _, err := os.Stat(p); err != nil {
+			return job, fmt.Errorf("%s 文件不存在: %s", kind, p)
+		}
+	}
+	// 按字段名排序，保证节点参数顺序稳定
+	sortNodeInfos(job.Inputs.Overrides)
+	return job, nil
+}
+
+// sortNodeInfos 按节点ID和字段名排序
+func sortNodeInfos(infos []NodeInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].NodeId != infos[j].NodeId {
+			return infos[i].NodeId < infos[j].NodeId
+		}
+		return infos[i].FieldName < infos[j].FieldName
+	})
+}
+
+// inputFiles 返回任务的本地输入文件
+func (j ManifestJob) inputFiles() []string {
+	var files []string
+	for _, kind := range []InputKind{InputImage, InputVideo, InputAudio} {
+		if p := j.Inputs.Files[kind]; p != "" {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// BatchProcessManifest 按清单批量执行任务，结果保存到当天的输出目录，返回本次运行记录
+func BatchProcessManifest(jobs []ManifestJob, concurrency int, executor *WorkflowExecutor) (*RunRecord, error) {
+	if len(jobs) == 0 {
+		logWarn("清单中没有任务")
+		return nil, nil
+	}
+	workflowID := jobs[0].WorkflowID
+	for _, job := range jobs {
+		if _, exists := executor.manager.GetWorkflow(job.WorkflowID); !exists {
+			return nil, fmt.Errorf("清单第 %d 行: 工作流不存在: %s", job.Line, job.WorkflowID)
+		}
+		if job.WorkflowID != workflowID {
+			workflowID = ""
+		}
+	}
+
+	logInfo("[清单] 获取任务", "count", len(jobs))
+	run := newRunRecord(RunKindManifest, workflowID, CreateOutputDir(), len(jobs))
+	run.start(executor)
+	runConcurrent(concurrency, len(jobs), func(i int) {
+		job := jobs[i]
+		run.Tasks[i] = runTask(taskJob{
+			tag:       "[清单]",
+			label:     job.Label(),
+			outputDir: run.OutputDir,
+			baseName:  job.BaseName(),
+			inputs:    job.inputFiles(),
+			create: func() (*TaskCreateResponse, error) {
+				return executor.ExecuteWorkflowWithInputs(job.WorkflowID, job.Inputs)
+			},
+		}, executor)
+	})
+	run.finish(executor)
+	logInfo("清单处理完成")
+	return run, nil
+}
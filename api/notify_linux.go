@@ -0,0 +1,75 @@
+//go:build linux
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask 目录内文件新建、写入完成、移入移出和删除时通知
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_DELETE
+
+// inotifyNotifier 基于 inotify 的目录变化通知
+type inotifyNotifier struct {
+	fd     int // 直接使用 fd 添加监控，File.Fd 会把文件切换为阻塞模式
+	file   *os.File
+	events chan struct{}
+	mu     sync.Mutex
+	dirs   map[string]bool
+}
+
+// newDirNotifier 创建目录变化通知，返回的通知只表示目录可能有变化，不区分具体文件
+func newDirNotifier() (dirNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 inotify 失败: %v", err)
+	}
+	n := &inotifyNotifier{
+		fd: fd,
+		// 非阻塞的文件描述符由运行时轮询，Close 时会中断读取
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan struct{}, 1),
+		dirs:   make(map[string]bool),
+	}
+	go n.read()
+	return n, nil
+}
+
+// read 读取 inotify 事件并合并为一个通知
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			close(n.events)
+			return
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inotifyNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+func (n *inotifyNotifier) Add(dir string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.dirs[dir] {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask); err != nil {
+		return fmt.Errorf("监控目录失败: %s: %v", dir, err)
+	}
+	n.dirs[dir] = true
+	return nil
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package api
+
+import "errors"
+
+// newDirNotifier 当前平台不支持目录变化通知，监控时只使用定时轮询
+func newDirNotifier() (dirNotifier, error) {
+	return nil, errors.New("当前平台不支持目录变化通知")
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// OutputSelector 输出筛选规则，只下载和转发符合条件的输出
+// 依次按节点、文件类型筛选，最后保留最后 Last 个
+type OutputSelector struct {
+	NodeIds   []string `json:"nodeIds,omitempty"`   // 只保留这些节点的输出
+	FileTypes []string `json:"fileTypes,omitempty"` // 只保留这些文件类型，可以是扩展名（如 mp4、png）或 image、video、audio
+	Last      int      `json:"last,omitempty"`      // 只保留最后 N 个输出，0 表示不限制
+}
+
+// IsZero 判断筛选规则是否为空
+func (s *OutputSelector) IsZero() bool {
+	return s == nil || (len(s.NodeIds) == 0 && len(s.FileTypes) == 0 && s.Last == 0)
+}
+
+// Apply 返回符合规则的输出，规则为空时返回全部输出
+func (s *OutputSelector) Apply(outputs []TaskOutput) []TaskOutput {
+	if s.IsZero() {
+		return outputs
+	}
+	selected := make([]TaskOutput, 0, len(outputs))
+	for _, output := range outputs {
+		if len(s.NodeIds) > 0 && !containsString(s.NodeIds, output.NodeId) {
+			continue
+		}
+		if len(s.FileTypes) > 0 && !matchFileType(output, s.FileTypes...) {
+			continue
+		}
+		selected = append(selected, output)
+	}
+	if s.Last > 0 && len(selected) > s.Last {
+		selected = selected[len(selected)-s.Last:]
+	}
+	return selected
+}
+
+// ParseOutputSelector 根据命令行参数生成筛选规则，参数均为空时返回 nil
+func ParseOutputSelector(nodeIds, fileTypes string, last int) *OutputSelector {
+	s := &OutputSelector{
+		NodeIds:   splitList(nodeIds),
+		FileTypes: splitList(fileTypes),
+		Last:      last,
+	}
+	if s.IsZero() {
+		return nil
+	}
+	return s
+}
+
+// outputExt 返回输出文件的扩展名（小写，不含点），优先使用接口返回的 fileType
+func outputExt(output TaskOutput) string {
+	if output.FileType != "" {
+		return strings.ToLower(strings.TrimPrefix(output.FileType, "."))
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(output.FileUrl), "."))
+}
+
+// matchFileType 判断输出是否属于任一文件类型
+func matchFileType(output TaskOutput, fileTypes ...string) bool {
+	ext := outputExt(output)
+	kind := InputKindOf("." + ext)
+	for _, t := range fileTypes {
+		t = strings.ToLower(strings.TrimPrefix(t, "."))
+		if t == ext || (kind != "" && t == string(kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputCondition 对阶段输出的判断条件，所有非空条件都满足时成立
+type OutputCondition struct {
+	FileType string `json:"fileType,omitempty"` // 只统计该类型的输出，可以是扩展名或 image、video、audio
+	NodeId   string `json:"nodeId,omitempty"`   // 只统计该节点的输出
+	MinCount int    `json:"minCount,omitempty"` // 符合条件的输出至少 N 个，未设置任何数量条件时默认为 1
+	MaxCount int    `json:"maxCount,omitempty"` // 符合条件的输出至多 N 个
+}
+
+// Match 判断输出是否满足条件
+func (c OutputCondition) Match(outputs []TaskOutput) bool {
+	count := 0
+	for _, output := range outputs {
+		if c.NodeId != "" && output.NodeId != c.NodeId {
+			continue
+		}
+		if c.FileType != "" && !matchFileType(output, c.FileType) {
+			continue
+		}
+		count++
+	}
+	minCount := c.MinCount
+	if minCount == 0 && c.MaxCount == 0 {
+		minCount = 1
+	}
+	if count < minCount {
+		return false
+	}
+	return c.MaxCount == 0 || count <= c.MaxCount
+}
+
+// String 返回条件的可读描述
+func (c OutputCondition) String() string {
+	var parts []string
+	if c.FileType != "" {
+		parts = append(parts, "fileType="+c.FileType)
+	}
+	if c.NodeId != "" {
+		parts = append(parts, "nodeId="+c.NodeId)
+	}
+	if c.MinCount > 0 {
+		parts = append(parts, fmt.Sprintf("count>=%d", c.MinCount))
+	}
+	if c.MaxCount > 0 {
+		parts = append(parts, fmt.Sprintf("count<=%d", c.MaxCount))
+	}
+	if len(parts) == 0 {
+		return "count>=1"
+	}
+	return strings.Join(parts, ",")
+}
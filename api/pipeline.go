@@ -0,0 +1,468 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PipelineStage 流水线中的一个阶段
+type PipelineStage struct {
+	Name       string                 `json:"name"`              // 阶段名称，供后续阶段引用
+	WorkflowID string                 `json:"workflow"`          // 工作流ID
+	Inputs     map[string]string      `json:"inputs,omitempty"`  // 输入映射，见 Pipeline 说明
+	Text       string                 `json:"text,omitempty"`    // 文本提示词
+	Fields     map[string]interface{} `json:"fields,omitempty"`  // 固定字段，key 为 <节点ID>.<字段名>
+	Outputs    *OutputSelector        `json:"outputs,omitempty"` // 输出筛选规则，只下载和转发保留的输出，为空时使用工作流配置
+	Next       []PipelineRoute        `json:"next,omitempty"`    // 后续阶段的选择条件，为空时继续执行下一个阶段
+}
+
+// PipelineEnd 路由到该名称时结束分支
+const PipelineEnd = "end"
+
+// PipelineRoute 根据阶段输出选择后续阶段，按顺序匹配第一条满足条件的路由
+type PipelineRoute struct {
+	When  *OutputCondition `json:"when,omitempty"` // 条件，为空时总是匹配
+	Stage string           `json:"stage"`          // 后续阶段名称，只能是之后的阶段，end 表示结束分支
+}
+
+// Pipeline 多阶段流水线，前一阶段的输出作为后续阶段的输入
+//
+// 阶段的 inputs 中，key 可以是 image、video、audio、text 或 <节点ID>.<字段名>，value 可以是:
+//   - <阶段名>.outputs[N]: 引用某个阶段的第 N 个输出文件，负数表示从末尾倒数
+//   - <阶段名>.outputs 或 <阶段名>.outputs[*]: 引用全部输出文件，每个文件各自执行一次后续流程（扇出）
+//   - input.<key>: 引用流水线的 inputs
+//   - 其他值视为本地文件路径或字面值
+//
+// 阶段的 next 按顺序匹配，跳转到第一条条件成立的路由指定的阶段，中间的阶段不执行；
+// 没有路由匹配时该分支结束
+type Pipeline struct {
+	Name   string            `json:"name"`             // 流水线名称
+	Inputs map[string]string `json:"inputs,omitempty"` // 流水线输入，相对路径以配置文件所在目录为基准
+	Stages []PipelineStage   `json:"stages"`           // 按顺序执行的阶段
+}
+
+// pipelineRefPattern 匹配 <阶段名>.outputs[...] 引用
+var pipelineRefPattern = regexp.MustCompile(`^([A-Za-z0-9_-]+)\.outputs(?:\[(\*|-?\d+)\])?$`)
+
+// pipelineRef 解析后的阶段输出引用
+type pipelineRef struct {
+	stage  string
+	index  int
+	fanOut bool
+}
+
+// parsePipelineRef 解析阶段输出引用，不是引用时返回 false
+func parsePipelineRef(value string) (pipelineRef, bool) {
+	m := pipelineRefPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil || m[1] == "input" {
+		return pipelineRef{}, false
+	}
+	if m[2] == "" || m[2] == "*" {
+		return pipelineRef{stage: m[1], fanOut: true}, true
+	}
+	index, _ := strconv.Atoi(m[2])
+	return pipelineRef{stage: m[1], index: index}, true
+}
+
+// LoadPipeline 读取 JSON 格式的流水线配置并校验阶段引用
+// inputs 覆盖配置中同名的流水线输入，如命令行指定的 -image
+func LoadPipeline(pipelinePath string, inputs map[string]string) (*Pipeline, error) {
+	data, err := os.ReadFile(pipelinePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取流水线配置失败: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	var p Pipeline
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("解析流水线配置失败: %v", err)
+	}
+	if p.Name == "" {
+		base := filepath.Base(pipelinePath)
+		p.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	baseDir := filepath.Dir(pipelinePath)
+	resolve := func(v string) string {
+		if v == "" || filepath.IsAbs(v) {
+			return v
+		}
+		if _, err := os.Stat(filepath.Join(baseDir, v)); err == nil {
+			return filepath.Join(baseDir, v)
+		}
+		return v
+	}
+	for key, v := range p.Inputs {
+		if key != "text" {
+			p.Inputs[key] = resolve(v)
+		}
+	}
+	for key, v := range inputs {
+		if v != "" {
+			if p.Inputs == nil {
+				p.Inputs = make(map[string]string)
+			}
+			p.Inputs[key] = v
+		}
+	}
+	for i := range p.Stages {
+		for key, v := range p.Stages[i].Inputs {
+			if _, ok := parsePipelineRef(v); !ok && key != "text" && !strings.HasPrefix(v, "input.") {
+				p.Stages[i].Inputs[key] = resolve(v)
+			}
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate 校验阶段名称和引用，引用只能指向之前的阶段，每个阶段最多一个扇出引用，
+// 且被引用的阶段在到达该阶段的每条路径上都会执行（不能被 next 跳过）
+func (p *Pipeline) Validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("流水线中没有阶段")
+	}
+	seen := make(map[string]bool)
+	for i := range p.Stages {
+		stage := &p.Stages[i]
+		if stage.Name == "" {
+			stage.Name = fmt.Sprintf("stage%d", i+1)
+		}
+		if stage.Name == "input" || seen[stage.Name] {
+			return fmt.Errorf("阶段名称重复或无效: %s", stage.Name)
+		}
+		if stage.WorkflowID == "" {
+			return fmt.Errorf("阶段 %s 未指定工作流ID", stage.Name)
+		}
+		fanOuts := 0
+		for key, v := range stage.Inputs {
+			if err := validateInputKey(key); err != nil {
+				return fmt.Errorf("阶段 %s: %v", stage.Name, err)
+			}
+			if strings.HasPrefix(v, "input.") {
+				if _, ok := p.Inputs[strings.TrimPrefix(v, "input.")]; !ok {
+					return fmt.Errorf("阶段 %s: 流水线输入不存在: %s", stage.Name, v)
+				}
+				continue
+			}
+			ref, ok := parsePipelineRef(v)
+			if !ok {
+				continue
+			}
+			if !seen[ref.stage] {
+				return fmt.Errorf("阶段 %s: 只能引用之前的阶段: %s", stage.Name, v)
+			}
+			if ref.fanOut {
+				fanOuts++
+			}
+		}
+		if fanOuts > 1 {
+			return fmt.Errorf("阶段 %s: 最多只能有一个扇出引用", stage.Name)
+		}
+		seen[stage.Name] = true
+	}
+	for _, stage := range p.Stages {
+		for _, route := range stage.Next {
+			if route.Stage == PipelineEnd {
+				continue
+			}
+			if seen[route.Stage] && p.stageIndex(route.Stage) > p.stageIndex(stage.Name) {
+				continue
+			}
+			return fmt.Errorf("阶段 %s: 只能跳转到之后的阶段: %s", stage.Name, route.Stage)
+		}
+	}
+
+	executed := p.executedBefore()
+	for i, stage := range p.Stages {
+		if executed[i] == nil {
+			// 没有路径能到达的阶段不会执行
+			continue
+		}
+		for _, v := range stage.Inputs {
+			if ref, ok := parsePipelineRef(v); ok && !executed[i][ref.stage] {
+				return fmt.Errorf("阶段 %s: 引用的阶段 %s 可能被 next 跳过: %s", stage.Name, ref.stage, v)
+			}
+		}
+	}
+	return nil
+}
+
+// executedBefore 返回每个阶段之前在所有路径上都会执行的阶段，没有路径能到达的阶段为 nil
+// 路由只能跳转到之后的阶段，按顺序计算各前驱集合的交集即可
+func (p *Pipeline) executedBefore() []map[string]bool {
+	executed := make([]map[string]bool, len(p.Stages))
+	executed[0] = make(map[string]bool)
+	for i, stage := range p.Stages {
+		if executed[i] == nil {
+			continue
+		}
+		var targets []int
+		if len(stage.Next) == 0 {
+			targets = append(targets, i+1)
+		}
+		for _, route := range stage.Next {
+			if route.Stage != PipelineEnd {
+				targets = append(targets, p.stageIndex(route.Stage))
+			}
+		}
+		for _, j := range targets {
+			if j >= len(p.Stages) {
+				continue
+			}
+			along := make(map[string]bool, len(executed[i])+1)
+			for name := range executed[i] {
+				along[name] = true
+			}
+			along[stage.Name] = true
+			if executed[j] == nil {
+				executed[j] = along
+				continue
+			}
+			for name := range executed[j] {
+				if !along[name] {
+					delete(executed[j], name)
+				}
+			}
+		}
+	}
+	return executed
+}
+
+// stageIndex 返回阶段的序号，不存在时返回 -1
+func (p *Pipeline) stageIndex(name string) int {
+	for i, stage := range p.Stages {
+		if stage.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextStage 根据阶段输出选择后续阶段的序号，返回 -1 表示结束分支
+func (p *Pipeline) nextStage(index int, outputs []TaskOutput) int {
+	stage := p.Stages[index]
+	if len(stage.Next) == 0 {
+		return index + 1
+	}
+	for _, route := range stage.Next {
+		if route.When != nil && !route.When.Match(outputs) {
+			continue
+		}
+		if route.Stage == PipelineEnd {
+			return -1
+		}
+		return p.stageIndex(route.Stage)
+	}
+	return -1
+}
+
+// validateInputKey 校验阶段输入的 key
+func validateInputKey(key string) error {
+	switch key {
+	case "image", "video", "audio", "text":
+		return nil
+	}
+	if nodeID, fieldName, ok := strings.Cut(key, "."); ok && nodeID != "" && fieldName != "" {
+		return nil
+	}
+	return fmt.Errorf("无法识别的输入: %s（支持 image、video、audio、text 或 <节点ID>.<字段名>）", key)
+}
+
+// pipelineBranch 流水线执行中的一条分支，记录已完成阶段的输出文件
+type pipelineBranch struct {
+	path    string              // 分支路径，如 1-2，用于文件名
+	outputs map[string][]string // 阶段名 -> 输出文件
+}
+
+// pipelineRunner 流水线执行器
+type pipelineRunner struct {
+	pipeline *Pipeline
+	executor *WorkflowExecutor
+	sem      chan struct{} // 所有分支共用的任务名额，嵌套扇出同样受 concurrency 限制
+	run      *RunRecord
+}
+
+// RunPipeline 执行流水线，结果保存到 outputs/<日期>/pipeline_<名称>_<时间>/<阶段名>/ 目录
+// 阶段引用的输出会重新上传作为下一阶段的输入；扇出时各分支以 concurrency 个并发执行
+func RunPipeline(p *Pipeline, concurrency int, executor *WorkflowExecutor) (*RunRecord, error) {
+	for _, stage := range p.Stages {
+		if _, exists := executor.manager.GetWorkflow(stage.WorkflowID); !exists {
+			return nil, fmt.Errorf("阶段 %s: 工作流不存在: %s", stage.Name, stage.WorkflowID)
+		}
+	}
+
+	dir := filepath.Join(CreateOutputDir(), fmt.Sprintf("pipeline_%s_%s", unsafeTagChars.ReplaceAllString(p.Name, "_"), time.Now().Format("20060102_150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建流水线目录失败: %v", err)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	runner := &pipelineRunner{
+		pipeline: p,
+		executor: executor,
+		sem:      make(chan struct{}, concurrency),
+		run:      newRunRecord(RunKindPipeline, "", dir, 0),
+	}
+	logInfo("[流水线] 开始执行", "name", p.Name, "stages", len(p.Stages), "dir", dir)
+	runner.run.start(executor)
+	runner.runStage(0, pipelineBranch{path: "1", outputs: make(map[string][]string)})
+	runner.run.finish(executor)
+	logInfo("流水线执行完成", "name", p.Name)
+	return runner.run, nil
+}
+
+// runStage 在分支上执行第 index 个阶段，成功后继续执行后续阶段
+func (r *pipelineRunner) runStage(index int, branch pipelineBranch) {
+	if index < 0 || index >= len(r.pipeline.Stages) {
+		return
+	}
+	stage := r.pipeline.Stages[index]
+
+	// 展开扇出引用，每个文件一条分支
+	fanKey, fanFiles := "", []string(nil)
+	for key, v := range stage.Inputs {
+		if ref, ok := parsePipelineRef(v); ok && ref.fanOut {
+			fanKey, fanFiles = key, branch.outputs[ref.stage]
+		}
+	}
+	if fanKey == "" {
+		r.runStageBranch(index, branch, "", "")
+		return
+	}
+	if len(fanFiles) == 0 {
+		logWarn("[流水线] 引用的阶段没有输出，跳过后续阶段", "stage", stage.Name, "branch", branch.path)
+		return
+	}
+	// 各分支只在执行任务时占用名额，等待子分支的分支不占名额，嵌套扇出不会死锁
+	var wg sync.WaitGroup
+	for i, file := range fanFiles {
+		child := branch
+		if len(fanFiles) > 1 {
+			child.path = fmt.Sprintf("%s-%d", branch.path, i+1)
+		}
+		wg.Add(1)
+		go func(child pipelineBranch, file string) {
+			defer wg.Done()
+			r.runStageBranch(index, child, fanKey, file)
+		}(child, file)
+	}
+	wg.Wait()
+}
+
+// runStageBranch 执行一个阶段的一条分支，fanKey 非空时使用 fanFile 作为该输入的值
+func (r *pipelineRunner) runStageBranch(index int, branch pipelineBranch, fanKey, fanFile string) {
+	stage := r.pipeline.Stages[index]
+	label := fmt.Sprintf("%s#%s", stage.Name, branch.path)
+
+	inputs, files, err := r.stageInputs(stage, branch, fanKey, fanFile)
+	if err != nil {
+		result := &TaskResult{Label: label, WorkflowID: stage.WorkflowID, Status: TaskStatusError, Error: err.Error(), Started: time.Now()}
+		r.run.add(result)
+		logError("[流水线] 解析阶段输入失败", "stage", label, "error", err)
+		return
+	}
+
+	r.sem <- struct{}{}
+	result := runTask(taskJob{
+		tag:       "[流水线]",
+		label:     label,
+		outputDir: filepath.Join(r.run.OutputDir, stage.Name),
+		baseName:  fmt.Sprintf("%s_%s", stage.Name, branch.path),
+		inputs:    files,
+		selector:  stage.Outputs,
+		// 后续阶段按序号引用输出文件，路由条件按序号检查输出，两者必须一一对应
+		allFiles: true,
+		create: func() (*TaskCreateResponse, error) {
+			return r.executor.ExecuteWorkflowWithInputs(stage.WorkflowID, inputs)
+		},
+	}, r.executor)
+	<-r.sem
+	r.run.add(result)
+	if !result.Succeeded() {
+		logWarn("[流水线] 阶段未成功，停止该分支", "stage", label, "status", result.Status)
+		return
+	}
+
+	// 复制已完成阶段的输出，避免并发分支共享同一个 map
+	next := pipelineBranch{path: branch.path, outputs: make(map[string][]string, len(branch.outputs)+1)}
+	for k, v := range branch.outputs {
+		next.outputs[k] = v
+	}
+	next.outputs[stage.Name] = result.Files
+
+	nextIndex := r.pipeline.nextStage(index, result.Outputs)
+	if nextIndex < 0 {
+		logInfo("[流水线] 分支结束", "stage", label, "outputs", len(result.Outputs))
+		return
+	}
+	if nextIndex != index+1 {
+		logInfo("[流水线] 按条件跳转", "stage", label, "next", r.pipeline.Stages[nextIndex].Name)
+	}
+	r.runStage(nextIndex, next)
+}
+
+// stageInputs 解析阶段输入，返回任务输入和其中的本地文件
+func (r *pipelineRunner) stageInputs(stage PipelineStage, branch pipelineBranch, fanKey, fanFile string) (TaskInputs, []string, error) {
+	inputs := TaskInputs{Files: make(map[InputKind]string), Text: stage.Text}
+	var files []string
+	for key, value := range stage.Fields {
+		nodeID, fieldName, ok := strings.Cut(key, ".")
+		if !ok || nodeID == "" || fieldName == "" {
+			return inputs, nil, fmt.Errorf("无法识别的固定字段: %s", key)
+		}
+		inputs.Overrides = append(inputs.Overrides, NodeInfo{NodeId: nodeID, FieldName: fieldName, FieldValue: value})
+	}
+
+	keys := make([]string, 0, len(stage.Inputs))
+	for key := range stage.Inputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		raw := stage.Inputs[key]
+		value := raw
+		if key == fanKey {
+			value = fanFile
+		} else if strings.HasPrefix(raw, "input.") {
+			value = r.pipeline.Inputs[strings.TrimPrefix(raw, "input.")]
+		} else if ref, ok := parsePipelineRef(raw); ok {
+			outputs := branch.outputs[ref.stage]
+			index := ref.index
+			if index < 0 {
+				index += len(outputs)
+			}
+			if index < 0 || index >= len(outputs) {
+				return inputs, nil, fmt.Errorf("阶段 %s 只有 %d 个输出，无法引用 %s", ref.stage, len(outputs), raw)
+			}
+			value = outputs[index]
+		}
+
+		switch key {
+		case "image", "video", "audio":
+			inputs.Files[InputKind(key)] = value
+			files = append(files, value)
+		case "text":
+			inputs.Text = value
+		default:
+			nodeID, fieldName, _ := strings.Cut(key, ".")
+			inputs.Overrides = append(inputs.Overrides, NodeInfo{NodeId: nodeID, FieldName: fieldName, FieldValue: value})
+		}
+	}
+	sortNodeInfos(inputs.Overrides)
+	return inputs, files, nil
+}
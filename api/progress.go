@@ -0,0 +1,44 @@
+package api
+
+import "time"
+
+// TaskStatusSubmitting 任务正在上传输入和提交，尚未拿到任务ID，只出现在进度通知中
+const TaskStatusSubmitting = "SUBMITTING"
+
+// TaskProgress 批量运行中单个任务的进度
+type TaskProgress struct {
+	Label   string    // 任务标识，如输入文件路径
+	TaskID  string    // 任务ID，提交中时为空
+	Status  string    // SUBMITTING 或服务器返回的状态（QUEUED、RUNNING 等）
+	Started time.Time // 任务开始处理的时间
+}
+
+// ProgressNotifier 批量运行的进度通知，Notifier 同时实现该接口时，
+// 执行器在运行开始、任务开始提交、任务创建成功和服务器状态变化时同步调用
+type ProgressNotifier interface {
+	RunStarted(run *RunRecord, total int) // 批量运行开始，total 为任务总数，事先未知时为 0
+	TaskProgress(progress TaskProgress)   // 任务进度变化，任务结束时调用 TaskFinished 而不是该方法
+}
+
+// start 发送运行开始通知，任务总数为预留的任务位置数
+func (r *RunRecord) start(executor *WorkflowExecutor) {
+	executor.notifyRunStarted(r, len(r.Tasks))
+}
+
+// notifyRunStarted 依次调用实现了 ProgressNotifier 的通知
+func (we *WorkflowExecutor) notifyRunStarted(run *RunRecord, total int) {
+	for _, n := range we.notifiers {
+		if p, ok := n.(ProgressNotifier); ok {
+			p.RunStarted(run, total)
+		}
+	}
+}
+
+// notifyProgress 依次调用实现了 ProgressNotifier 的通知
+func (we *WorkflowExecutor) notifyProgress(progress TaskProgress) {
+	for _, n := range we.notifiers {
+		if p, ok := n.(ProgressNotifier); ok {
+			p.TaskProgress(progress)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaskRecord 任务登记表中的一个任务
+type TaskRecord struct {
+	TaskID       string       `json:"taskId"`
+	WorkflowID   string       `json:"workflowId,omitempty"`
+	Label        string       `json:"label,omitempty"`        // 任务标识，如输入文件路径
+	Inputs       []string     `json:"inputs,omitempty"`       // 本地输入文件
+	Profile      string       `json:"profile,omitempty"`      // 使用 Key 池时创建任务的账户
+	OutputDir    string       `json:"outputDir,omitempty"`    // 结果保存目录
+	BaseName     string       `json:"baseName,omitempty"`     // 结果文件名前缀，为空时使用任务ID
+	Seed         *int64       `json:"seed,omitempty"`         // 节点参数中的随机种子
+	NodeInfoList []NodeInfo   `json:"nodeInfoList,omitempty"` // 提交的节点参数，rerun 时原样重新提交
+	RerunOf      string       `json:"rerunOf,omitempty"`      // 由 rerun 重新提交时为原任务ID
+	Status       string       `json:"status"`                 // 最近一次得知的状态
+	Error        string       `json:"error,omitempty"`
+	Files        []string     `json:"files,omitempty"` // 已保存的本地文件
+	Hooks        []HookResult `json:"hooks,omitempty"` // 任务结束后执行的钩子及其退出码
+	Created      time.Time    `json:"created"`
+	Updated      time.Time    `json:"updated"`
+}
+
+// Finished 判断任务是否已结束（服务器返回 SUCCESS、FAILED，已取消或超时取消）
+// ERROR 表示本地出错，任务可能仍在服务器上执行，不视为结束
+func (r *TaskRecord) Finished() bool {
+	switch r.Status {
+	case "SUCCESS", "FAILED", TaskStatusCanceled, TaskStatusTimeout:
+		return true
+	}
+	return false
+}
+
+// TaskRegistry 本工具创建的所有任务的登记表，保存为 JSON Lines 文件
+// 每次更新追加一行完整记录，读取时同一任务以最后一行为准；多个进程可以同时追加
+// 追加时对锁文件（登记表路径加 .lock）加共享锁，压缩时加排他锁，压缩期间其他进程的写入会等待压缩完成
+type TaskRegistry struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultTaskRegistryPath 返回默认的登记表路径
+// ~/.local/state/runninghub/tasks.jsonl（设置了 XDG_STATE_HOME 时位于该目录下）
+func DefaultTaskRegistryPath() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "runninghub", "tasks.jsonl")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "runninghub", "tasks.jsonl")
+	}
+	return filepath.Join(".runninghub", "tasks.jsonl")
+}
+
+// OpenTaskRegistry 打开登记表，文件不存在时在第一次写入时创建
+func OpenTaskRegistry(path string) (*TaskRegistry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建任务登记表目录失败: %v", err)
+	}
+	return &TaskRegistry{path: path}, nil
+}
+
+// Path 返回登记表文件路径
+func (r *TaskRegistry) Path() string {
+	return r.path
+}
+
+// lockPath 返回进程间共用的锁文件路径
+func (r *TaskRegistry) lockPath() string {
+	return r.path + ".lock"
+}
+
+// Put 写入任务的最新记录，Updated 设置为当前时间
+func (r *TaskRegistry) Put(record *TaskRecord) error {
+	record.Updated = time.Now()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化任务记录失败: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unlock, err := lockFile(r.lockPath(), false)
+	if err != nil {
+		return fmt.Errorf("锁定任务登记表失败: %v", err)
+	}
+	defer unlock()
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开任务登记表失败: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入任务登记表失败: %v", err)
+	}
+	return nil
+}
+
+// Get 返回任务的最新记录
+func (r *TaskRegistry) Get(taskID string) (*TaskRecord, bool, error) {
+	records, err := r.List(TaskFilter{})
+	if err != nil {
+		return nil, false, err
+	}
+	for _, record := range records {
+		if record.TaskID == taskID {
+			return record, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// TaskFilter 登记表查询条件，零值字段不参与过滤
+type TaskFilter struct {
+	Statuses   []string  // 状态，不区分大小写
+	WorkflowID string    // 工作流ID
+	Since      time.Time // 创建时间不早于
+	Until      time.Time // 创建时间早于
+	Finished   bool      // 只匹配已结束的任务（见 TaskRecord.Finished）
+}
+
+// Match 判断记录是否满足条件
+func (f TaskFilter) Match(record *TaskRecord) bool {
+	if len(f.Statuses) > 0 {
+		matched := false
+		for _, status := range f.Statuses {
+			if strings.EqualFold(record.Status, status) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Finished && !record.Finished() {
+		return false
+	}
+	if f.WorkflowID != "" && record.WorkflowID != f.WorkflowID {
+		return false
+	}
+	if !f.Since.IsZero() && record.Created.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !record.Created.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// List 按创建时间返回满足条件的任务的最新记录
+func (r *TaskRegistry) List(filter TaskFilter) ([]*TaskRecord, error) {
+	r.mu.Lock()
+	records, err := r.load()
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var matched []*TaskRecord
+	for _, record := range records {
+		if filter.Match(record) {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
+
+// Purge 删除满足条件的任务并压缩登记表，返回删除的数量
+func (r *TaskRegistry) Purge(filter TaskFilter) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 其他进程的 Put 在压缩完成前等待，不会写入被替换掉的旧文件
+	unlock, err := lockFile(r.lockPath(), true)
+	if err != nil {
+		return 0, fmt.Errorf("锁定任务登记表失败: %v", err)
+	}
+	defer unlock()
+	records, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+	var kept []*TaskRecord
+	for _, record := range records {
+		if !filter.Match(record) {
+			kept = append(kept, record)
+		}
+	}
+	if err := r.rewrite(kept); err != nil {
+		return 0, err
+	}
+	return len(records) - len(kept), nil
+}
+
+// load 读取登记表，合并同一任务的多行记录，按创建时间排序，调用方需持有锁
+func (r *TaskRegistry) load() ([]*TaskRecord, error) {
+	file, err := os.Open(r.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开任务登记表失败: %v", err)
+	}
+	defer file.Close()
+
+	latest := make(map[string]*TaskRecord)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		// 按原样保留节点参数中的数字，避免大的随机种子在 rerun 时丢失精度
+		var record TaskRecord
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil || record.TaskID == "" {
+			logWarn("跳过无法解析的任务记录", "path", r.path, "line", line, "error", err)
+			continue
+		}
+		latest[record.TaskID] = &record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取任务登记表失败: %v", err)
+	}
+
+	records := make([]*TaskRecord, 0, len(latest))
+	for _, record := range latest {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Created.Equal(records[j].Created) {
+			return records[i].Created.Before(records[j].Created)
+		}
+		return records[i].TaskID < records[j].TaskID
+	})
+	return records, nil
+}
+
+// rewrite 用 records 替换登记表内容，调用方需持有锁
+func (r *TaskRegistry) rewrite(records []*TaskRecord) error {
+	var b strings.Builder
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("序列化任务记录失败: %v", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("写入任务登记表失败: %v", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("写入任务登记表失败: %v", err)
+	}
+	return nil
+}
+
+// taskRegistry 当前使用的任务登记表，为 nil 时不登记任务
+var (
+	taskRegistry   *TaskRegistry
+	taskRegistryMu sync.RWMutex
+)
+
+// SetTaskRegistry 设置任务登记表，nil 表示不登记任务
+func SetTaskRegistry(registry *TaskRegistry) {
+	taskRegistryMu.Lock()
+	taskRegistry = registry
+	taskRegistryMu.Unlock()
+}
+
+// GetTaskRegistry 返回当前的任务登记表，可能为 nil
+func GetTaskRegistry() *TaskRegistry {
+	taskRegistryMu.RLock()
+	defer taskRegistryMu.RUnlock()
+	return taskRegistry
+}
+
+// RecordTask 把任务记录写入当前的登记表，未设置登记表时不做任何事，写入失败只记录日志
+func RecordTask(record *TaskRecord) {
+	registry := GetTaskRegistry()
+	if registry == nil || record.TaskID == "" {
+		return
+	}
+	if err := registry.Put(record); err != nil {
+		logWarn("登记任务失败", "taskId", record.TaskID, "error", err)
+	}
+}
+
+// recordJob 根据批量任务的当前结果登记任务，status 为空时使用结果的状态
+func recordJob(job taskJob, result *TaskResult, status string) {
+	if status == "" {
+		status = result.Status
+	}
+	baseName := job.baseName
+	if result.Seed != nil {
+		baseName = OutputBaseName(job.baseName, result.TaskID, result.Seed)
+	}
+	RecordTask(&TaskRecord{
+		TaskID:       result.TaskID,
+		WorkflowID:   result.WorkflowID,
+		Label:        job.label,
+		Inputs:       job.inputs,
+		Profile:      result.Profile,
+		OutputDir:    job.outputDir,
+		BaseName:     baseName,
+		Seed:         result.Seed,
+		NodeInfoList: result.NodeInfoList,
+		RerunOf:      job.rerunOf,
+		Status:       status,
+		Error:        result.Error,
+		Files:        result.Files,
+		Hooks:        result.Hooks,
+		Created:      result.Started,
+	})
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reportMedia 报告中展示的一个本地文件
+type reportMedia struct {
+	Name string    // 文件名
+	Href string    // 相对报告的路径
+	Kind InputKind // 文件类型，无法识别时为空
+}
+
+// reportTask 报告中的一个任务
+type reportTask struct {
+	*TaskResult
+	InputMedia  []reportMedia
+	OutputMedia []reportMedia
+}
+
+// reportData 报告模板数据
+type reportData struct {
+	Run   *RunRecord
+	Stats RunStats
+	Tasks []reportTask
+}
+
+// ReportPaths 返回运行记录对应的 HTML 和 Markdown 报告路径
+func ReportPaths(run *RunRecord) (htmlPath, mdPath string) {
+	base := filepath.Join(run.OutputDir, "report_"+run.ID)
+	return base + ".html", base + ".md"
+}
+
+// WriteReport 根据运行记录生成静态 HTML 报告，markdown 为 true 时同时生成 Markdown 摘要
+// 报告保存在运行的输出目录中，引用的输入和输出文件均使用相对路径，返回生成的文件路径
+func WriteReport(run *RunRecord, markdown bool) ([]string, error) {
+	htmlPath, mdPath := ReportPaths(run)
+	data := newReportData(run, filepath.Dir(htmlPath))
+
+	file, err := os.Create(htmlPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建报告文件失败: %v", err)
+	}
+	if err := reportTemplate.Execute(file, data); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("生成报告失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("写入报告失败: %v", err)
+	}
+	paths := []string{htmlPath}
+
+	if markdown {
+		if err := os.WriteFile(mdPath, []byte(reportMarkdown(data)), 0644); err != nil {
+			return paths, fmt.Errorf("写入 Markdown 报告失败: %v", err)
+		}
+		paths = append(paths, mdPath)
+	}
+	return paths, nil
+}
+
+// newReportData 整理报告数据，文件路径转换为相对 dir 的路径
+func newReportData(run *RunRecord, dir string) reportData {
+	data := reportData{Run: run, Stats: run.Stats()}
+	for _, task := range run.Tasks {
+		if task == nil {
+			continue
+		}
+		rt := reportTask{TaskResult: task}
+		for _, input := range task.Inputs {
+			// 输入文件已被移动时展示新位置
+			if task.InputMoved != "" && len(task.Inputs) == 1 {
+				input = task.InputMoved
+			}
+			rt.InputMedia = append(rt.InputMedia, newReportMedia(dir, input))
+		}
+		for _, f := range task.Files {
+			rt.OutputMedia = append(rt.OutputMedia, newReportMedia(dir, f))
+		}
+		data.Tasks = append(data.Tasks, rt)
+	}
+	return data
+}
+
+func newReportMedia(dir, p string) reportMedia {
+	href := p
+	if abs, err := filepath.Abs(p); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(absDir, abs); err == nil {
+				href = rel
+			}
+		}
+	}
+	return reportMedia{
+		Name: filepath.Base(p),
+		Href: filepath.ToSlash(href),
+		Kind: InputKindOf(p),
+	}
+}
+
+// formatDuration 格式化耗时，精确到秒
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// formatCoins 格式化金币数量，去掉多余的小数位
+func formatCoins(c Coins) string {
+	return c.String()
+}
+
+// reportMarkdown 生成 Markdown 摘要
+func reportMarkdown(data reportData) string {
+	run, stats := data.Run, data.Stats
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 批量运行报告: %s\n\n", run.ID)
+	fmt.Fprintf(&b, "- 类型: %s\n", run.Kind)
+	if run.WorkflowID != "" {
+		fmt.Fprintf(&b, "- 工作流: `%s`\n", run.WorkflowID)
+	}
+	fmt.Fprintf(&b, "- 开始时间: %s\n", run.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 总耗时: %s\n", formatDuration(run.Duration()))
+	fmt.Fprintf(&b, "- 任务: %d（成功 %d，失败 %d", stats.Total, stats.Succeeded, stats.Failed)
+	if stats.TimedOut > 0 {
+		fmt.Fprintf(&b, "，其中超时 %d", stats.TimedOut)
+	}
+	if stats.Canceled > 0 {
+		fmt.Fprintf(&b, "，已取消 %d", stats.Canceled)
+	}
+	if stats.Skipped > 0 {
+		fmt.Fprintf(&b, "，已跳过 %d", stats.Skipped)
+	}
+	if stats.Detached > 0 {
+		fmt.Fprintf(&b, "，未等待结束 %d", stats.Detached)
+	}
+	b.WriteString("）\n")
+	fmt.Fprintf(&b, "- 输出文件: %d\n", stats.Files)
+	fmt.Fprintf(&b, "- 消耗金币: %s\n\n", formatCoins(stats.Coins))
+
+	b.WriteString("| 任务 | 状态 | 任务ID | 耗时 | 金币 | 输入 | 结果 |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	links := func(media []reportMedia) string {
+		parts := make([]string, 0, len(media))
+		for _, m := range media {
+			parts = append(parts, fmt.Sprintf("[%s](%s)", m.Name, m.Href))
+		}
+		return strings.Join(parts, "<br>")
+	}
+	for _, t := range data.Tasks {
+		status := t.Status
+		if t.Error != "" {
+			status += "<br>" + markdownCell(t.Error)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(t.Label), status, t.TaskID, formatDuration(t.Duration),
+			formatCoins(t.Coins), links(t.InputMedia), links(t.OutputMedia))
+	}
+	return b.String()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"coins":    formatCoins,
+	"datetime": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"lower":    strings.ToLower,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>批量运行报告 {{.Run.ID}}</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; background: #f6f7f9; }
+h1 { font-size: 22px; }
+.summary { display: flex; flex-wrap: wrap; gap: 12px; margin-bottom: 24px; }
+.summary div { background: #fff; border-radius: 6px; padding: 10px 16px; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+.summary b { display: block; font-size: 18px; }
+.task { background: #fff; border-radius: 6px; padding: 16px; margin-bottom: 16px; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+.task h2 { font-size: 16px; margin: 0 0 8px; word-break: break-all; }
+.meta { color: #666; font-size: 13px; margin-bottom: 12px; }
+.meta span { margin-right: 16px; }
+.status { display: inline-block; padding: 1px 8px; border-radius: 10px; color: #fff; font-size: 12px; }
+.status.success { background: #2e9d5b; }
+.status.failed, .status.error { background: #d9463e; }
+.error { color: #d9463e; font-size: 13px; margin-bottom: 12px; }
+.row { display: flex; gap: 24px; flex-wrap: wrap; }
+.col h3 { font-size: 13px; color: #666; margin: 0 0 6px; }
+.media { display: flex; gap: 8px; flex-wrap: wrap; }
+.media img, .media video { max-width: 320px; max-height: 320px; border-radius: 4px; background: #eee; }
+.media a { font-size: 13px; }
+details { font-size: 13px; margin-top: 12px; }
+table { border-collapse: collapse; }
+td { border: 1px solid #ddd; padding: 2px 8px; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>批量运行报告 {{.Run.ID}}</h1>
+<div class="summary">
+<div>类型<b>{{.Run.Kind}}</b></div>
+{{if .Run.WorkflowID}}<div>工作流<b>{{.Run.WorkflowID}}</b></div>{{end}}
+<div>开始时间<b>{{datetime .Run.Started}}</b></div>
+<div>总耗时<b>{{duration .Run.Duration}}</b></div>
+<div>任务<b>{{.Stats.Total}}</b></div>
+<div>成功<b>{{.Stats.Succeeded}}</b></div>
+<div>失败<b>{{.Stats.Failed}}</b></div>
+{{if .Stats.TimedOut}}<div>其中超时<b>{{.Stats.TimedOut}}</b></div>{{end}}
+{{if .Stats.Canceled}}<div>已取消<b>{{.Stats.Canceled}}</b></div>{{end}}
+{{if .Stats.Skipped}}<div>已跳过<b>{{.Stats.Skipped}}</b></div>{{end}}
+{{if .Stats.Detached}}<div>未等待结束<b>{{.Stats.Detached}}</b></div>{{end}}
+<div>输出文件<b>{{.Stats.Files}}</b></div>
+<div>消耗金币<b>{{coins .Stats.Coins}}</b></div>
+</div>
+{{define "media"}}<div class="media">{{range .}}{{if eq .Kind "image"}}<a href="{{.Href}}"><img src="{{.Href}}" alt="{{.Name}}" loading="lazy"></a>{{else if eq .Kind "video"}}<video src="{{.Href}}" controls preload="metadata"></video>{{else if eq .Kind "audio"}}<audio src="{{.Href}}" controls></audio>{{else}}<a href="{{.Href}}">{{.Name}}</a>{{end}}{{else}}<span>无</span>{{end}}</div>{{end}}
+{{range .Tasks}}
+<div class="task">
+<h2>{{.Label}}</h2>
+<div class="meta">
+<span class="status {{lower .Status}}">{{.Status}}</span>
+{{if .TaskID}}<span>任务ID: {{.TaskID}}</span>{{end}}
+<span>耗时: {{duration .Duration}}</span>
+<span>金币: {{coins .Coins}}</span>
+<span>开始: {{datetime .Started}}</span>
+</div>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+<div class="row">
+{{if .InputMedia}}<div class="col"><h3>输入</h3>{{template "media" .InputMedia}}</div>{{end}}
+<div class="col"><h3>结果</h3>{{template "media" .OutputMedia}}</div>
+</div>
+{{if .NodeInfoList}}<details><summary>参数</summary><table>
+{{range .NodeInfoList}}<tr><td>{{.NodeId}}</td><td>{{.FieldName}}</td><td>{{.FieldValue}}</td></tr>
+{{end}}</table></details>{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy 任务在服务器上执行失败（FAILED）时自动重新提交的规则
+// 本地出错（上传失败、查询失败等）、被取消或运行被中断的任务不会重试
+type RetryPolicy struct {
+	Attempts  int           `json:"attempts"`            // 失败后最多重新提交的次数，0 表示不重试
+	Delay     time.Duration `json:"delay,omitempty"`     // 重新提交前等待的时间
+	VarySeed  bool          `json:"varySeed,omitempty"`  // 重新提交时为随机种子字段换用新的随机种子
+	OnTimeout bool          `json:"onTimeout,omitempty"` // 超时取消（TIMEOUT）的任务同样重新提交
+}
+
+// retryable 判断任务结束时的错误是否应按该规则重新提交
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.IsZero() {
+		return false
+	}
+	return errors.Is(err, ErrTaskFailed) || (p.OnTimeout && errors.Is(err, ErrTaskTimeout))
+}
+
+// IsZero 判断是否不重试
+func (p *RetryPolicy) IsZero() bool {
+	return p == nil || p.Attempts <= 0
+}
+
+// SetRetryPolicy 设置本次运行的重试规则，覆盖各工作流的配置，nil 表示使用工作流配置
+func (we *WorkflowExecutor) SetRetryPolicy(policy *RetryPolicy) {
+	we.retry = policy
+}
+
+// RetryPolicy 返回工作流生效的重试规则，可能为 nil
+func (we *WorkflowExecutor) RetryPolicy(workflowID string) *RetryPolicy {
+	if we.retry != nil {
+		return we.retry
+	}
+	if config, exists := we.manager.GetWorkflow(workflowID); exists {
+		return config.Retry
+	}
+	return nil
+}
+
+// resubmit 等待 policy.Delay 后用上次提交的节点参数重新创建任务，apiKey 为创建失败任务的账户的 Key（已上传的文件属于该账户）
+// 等待期间运行被中断时返回 ErrTaskCanceled
+func (we *WorkflowExecutor) resubmit(result *TaskResult, apiKey string, policy *RetryPolicy) (*TaskCreateResponse, error) {
+	if policy.Delay > 0 {
+		select {
+		case <-time.After(policy.Delay):
+		case <-we.interrupted():
+			return nil, fmt.Errorf("%w: 运行已中断，不再重新提交", ErrTaskCanceled)
+		}
+	}
+	nodeInfoList := append([]NodeInfo(nil), result.NodeInfoList...)
+	if policy.VarySeed {
+		if config, exists := we.manager.GetWorkflow(result.WorkflowID); exists {
+			nodeInfoList = we.setSeed(config, nodeInfoList, randomSeed(), nil)
+		} else {
+			logWarn("工作流未注册，无法更换随机种子，使用相同参数重新提交", "workflowId", result.WorkflowID, "taskId", result.TaskID)
+		}
+	}
+	resp, err := withApiKey(apiKey, func(apiKey string) (*TaskCreateResponse, error) {
+		return createAdvancedTask(apiKey, result.WorkflowID, nodeInfoList)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 || resp.Data.TaskId == "" {
+		return nil, fmt.Errorf("code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	return resp, nil
+}
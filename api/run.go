@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 批量运行类型
+const (
+	RunKindImages   = "images"   // 批量处理输入目录
+	RunKindText     = "text"     // 批量处理文本
+	RunKindManifest = "manifest" // 按清单批量处理
+	RunKindSweep    = "sweep"    // 参数扫描
+	RunKindPipeline = "pipeline" // 多阶段流水线
+	RunKindWatch    = "watch"    // 监控输入目录
+)
+
+// RunRecord 一次批量运行的元数据，保存为输出目录下的 run_<ID>.json
+type RunRecord struct {
+	ID          string        `json:"id"`                   // 运行ID，由开始时间和类型组成
+	Kind        string        `json:"kind"`                 // 运行类型
+	WorkflowID  string        `json:"workflowId,omitempty"` // 工作流ID，清单中混合多个工作流时为空
+	OutputDir   string        `json:"outputDir"`            // 输出目录
+	Started     time.Time     `json:"started"`
+	Finished    time.Time     `json:"finished"`
+	Tasks       []*TaskResult `json:"tasks"`                 // 按提交顺序排列的任务结果
+	Hooks       []HookResult  `json:"hooks,omitempty"`       // 运行结束后执行的钩子
+	Interrupted bool          `json:"interrupted,omitempty"` // 运行被中断，未提交的任务为 SKIPPED，执行中的任务按中断策略为 CANCELED 或 DETACHED
+
+	mu sync.Mutex // 保护 add 追加 Tasks 和 Save 并发执行
+}
+
+// newRunRecord 创建运行记录，为 count 个任务预留位置，各任务按序号并发写入
+func newRunRecord(kind, workflowID, outputDir string, count int) *RunRecord {
+	started := time.Now()
+	return &RunRecord{
+		ID:         started.Format("20060102_150405") + "_" + kind,
+		Kind:       kind,
+		WorkflowID: workflowID,
+		OutputDir:  outputDir,
+		Started:    started,
+		Tasks:      make([]*TaskResult, count),
+	}
+}
+
+// add 追加任务结果，用于任务数量事先未知的运行（如流水线扇出）
+func (r *RunRecord) add(result *TaskResult) {
+	r.mu.Lock()
+	r.Tasks = append(r.Tasks, result)
+	r.mu.Unlock()
+}
+
+// Path 返回运行记录文件路径
+func (r *RunRecord) Path() string {
+	return filepath.Join(r.OutputDir, "run_"+r.ID+".json")
+}
+
+// Duration 返回运行总耗时
+func (r *RunRecord) Duration() time.Duration {
+	if r.Finished.IsZero() {
+		return time.Since(r.Started)
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// finish 记录结束时间并保存运行记录，然后发送运行结束通知
+// 通知可能修改运行记录（如记录钩子的执行结果），有通知时通知后再保存一次
+func (r *RunRecord) finish(executor *WorkflowExecutor) {
+	r.Finished = time.Now()
+	r.Interrupted = executor.Interrupted()
+	if err := r.Save(); err != nil {
+		logError("保存运行记录失败", "path", r.Path(), "error", err)
+		return
+	}
+	logInfo("运行记录已保存", "path", r.Path())
+	if len(executor.notifiers) == 0 {
+		return
+	}
+	executor.notifyRun(r)
+	if err := r.Save(); err != nil {
+		logError("保存运行记录失败", "path", r.Path(), "error", err)
+	}
+}
+
+// Save 将运行记录写入 Path()
+func (r *RunRecord) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化运行记录失败: %v", err)
+	}
+	if err := os.WriteFile(r.Path(), data, 0644); err != nil {
+		return fmt.Errorf("写入运行记录失败: %v", err)
+	}
+	return nil
+}
+
+// RunStats 运行统计
+type RunStats struct {
+	Total     int   // 任务总数
+	Succeeded int   // 成功数
+	Failed    int   // 失败数（服务器返回 FAILED、超时或本地出错）
+	TimedOut  int   // 超时取消的任务数，已计入 Failed
+	Canceled  int   // 已取消数
+	Skipped   int   // 未提交的任务数
+	Detached  int   // 运行中断时停止等待、仍在服务器上执行的任务数
+	Files     int   // 已保存的输出文件数
+	Coins     Coins // 消耗的金币
+}
+
+// Stats 统计运行结果
+func (r *RunRecord) Stats() RunStats {
+	var stats RunStats
+	for _, task := range r.Tasks {
+		if task == nil {
+			continue
+		}
+		stats.Total++
+		switch {
+		case task.Succeeded():
+			stats.Succeeded++
+		case task.Status == TaskStatusCanceled:
+			stats.Canceled++
+		case task.Status == TaskStatusSkipped:
+			stats.Skipped++
+		case task.Status == TaskStatusDetached:
+			stats.Detached++
+		default:
+			stats.Failed++
+			if task.Status == TaskStatusTimeout {
+				stats.TimedOut++
+			}
+		}
+		stats.Files += len(task.Files)
+		stats.Coins += task.Coins
+	}
+	return stats
+}
+
+// LoadRunRecord 读取运行记录
+func LoadRunRecord(path string) (*RunRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取运行记录失败: %v", err)
+	}
+	var record RunRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析运行记录失败: %v", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// SeedMode 随机种子的生成方式
+type SeedMode string
+
+const (
+	SeedFixed     SeedMode = "fixed"     // 所有任务使用同一个种子
+	SeedRandom    SeedMode = "random"    // 每个任务使用新的随机种子
+	SeedIncrement SeedMode = "increment" // 从起始值开始，每提交一个任务加 1
+)
+
+// ParseSeedMode 解析种子模式
+func ParseSeedMode(s string) (SeedMode, error) {
+	switch mode := SeedMode(s); mode {
+	case SeedFixed, SeedRandom, SeedIncrement:
+		return mode, nil
+	}
+	return "", fmt.Errorf("无效的种子模式: %s（可选 fixed, random, increment）", s)
+}
+
+// maxRandomSeed 随机种子的上限，保证种子在 JSON 中不丢失精度
+const maxRandomSeed = 1 << 50
+
+// randomSeed 生成随机种子
+func randomSeed() int64 {
+	return rand.Int63n(maxRandomSeed)
+}
+
+// SeedPolicy 本次运行为随机种子字段设置种子的规则，清单中指定了 seed 的任务不受影响
+type SeedPolicy struct {
+	Mode  SeedMode // 种子模式
+	Start int64    // fixed 模式使用的种子，increment 模式的第一个种子
+
+	mu     sync.Mutex
+	issued int64     // 已分配的种子数
+	warned sync.Once // 工作流没有种子字段时只警告一次
+}
+
+// Next 返回下一个任务使用的种子，并发调用时按调用顺序分配
+func (p *SeedPolicy) Next() int64 {
+	switch p.Mode {
+	case SeedRandom:
+		return randomSeed()
+	case SeedIncrement:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		seed := p.Start + p.issued
+		p.issued++
+		return seed
+	}
+	return p.Start
+}
+
+// SetSeedPolicy 设置本次运行的种子规则，nil 表示使用工作流配置中的种子
+func (we *WorkflowExecutor) SetSeedPolicy(policy *SeedPolicy) {
+	we.seed = policy
+}
+
+// isSeed 判断参数是否为随机种子字段
+func (p NodeParam) isSeed() bool {
+	return p.IsSeed || seedFieldNames[p.FieldName]
+}
+
+// isSeedField 判断节点字段是否为随机种子字段，config 可为 nil
+func (c *WorkflowConfig) isSeedField(nodeID, fieldName string) bool {
+	if seedFieldNames[fieldName] {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	for _, param := range c.Params {
+		if param.NodeId == nodeID && param.FieldName == fieldName && param.IsSeed {
+			return true
+		}
+	}
+	return false
+}
+
+// seedFieldCache 按工作流缓存从工作流 JSON 中找到的随机种子字段
+type seedFieldCache struct {
+	mu     sync.Mutex
+	fields map[string][]NodeParam // 工作流ID -> 种子字段，获取失败时同样记录（为 nil），不再重复请求
+}
+
+// lookup 返回工作流 JSON 中的随机种子字段，第一次调用时请求工作流 JSON
+func (c *seedFieldCache) lookup(workflowID string) []NodeParam {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fields, ok := c.fields[workflowID]; ok {
+		return fields
+	}
+	var fields []NodeParam
+	nodes, err := GetWorkflowJSON(workflowID)
+	if err != nil {
+		logWarn("获取工作流 JSON 失败，无法查找随机种子字段", "workflowId", workflowID, "error", err)
+	} else {
+		fields = findSeedFields(nodes)
+		logDebug("工作流 JSON 中的随机种子字段", "workflowId", workflowID, "fields", fields)
+	}
+	if c.fields == nil {
+		c.fields = make(map[string][]NodeParam)
+	}
+	c.fields[workflowID] = fields
+	return fields
+}
+
+// findSeedFields 返回节点中值为数字的 seed/noise_seed 字段（如 KSampler 的 seed、SamplerCustom 的 noise_seed），按节点ID排序
+// 连接到其他节点的字段（值为 [节点ID, 输出序号]）由上游节点决定，不视为种子字段
+func findSeedFields(nodes map[string]WorkflowNode) []NodeParam {
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var fields []NodeParam
+	for _, id := range ids {
+		for _, name := range []string{"seed", "noise_seed"} {
+			switch nodes[id].Inputs[name].(type) {
+			case json.Number, float64:
+				fields = append(fields, NodeParam{NodeId: id, FieldName: name, IsSeed: true})
+			}
+		}
+	}
+	return fields
+}
+
+// SeedFields 返回工作流的随机种子字段：工作流配置中的种子参数（IsSeed 或字段名为 seed/noise_seed），
+// 没有时从工作流的 API 格式 JSON 中查找，查找结果按工作流缓存，找不到时返回 nil
+func (we *WorkflowExecutor) SeedFields(config *WorkflowConfig) []NodeParam {
+	var fields []NodeParam
+	for _, param := range config.Params {
+		if param.isSeed() {
+			fields = append(fields, param)
+		}
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	return we.seeds.lookup(config.ID)
+}
+
+// nextSeed 按种子规则为工作流的新任务分配种子，未设置规则或工作流没有种子字段时返回 nil
+func (we *WorkflowExecutor) nextSeed(config *WorkflowConfig) *int64 {
+	if we.seed == nil {
+		return nil
+	}
+	if len(we.SeedFields(config)) == 0 {
+		we.seed.warned.Do(func() {
+			logWarn("工作流没有随机种子字段，忽略种子设置", "workflowId", config.ID)
+		})
+		return nil
+	}
+	seed := we.seed.Next()
+	return &seed
+}
+
+// applySeed 按种子规则设置节点参数中的随机种子字段，返回设置后的节点参数
+func (we *WorkflowExecutor) applySeed(config *WorkflowConfig, nodeInfoList []NodeInfo) []NodeInfo {
+	seed := we.nextSeed(config)
+	if seed == nil {
+		return nodeInfoList
+	}
+	return we.setSeed(config, nodeInfoList, *seed, nil)
+}
+
+// setSeed 把 seed 写入工作流的所有随机种子字段，节点参数中没有的字段追加到末尾，keep 中指定的字段保持原值
+func (we *WorkflowExecutor) setSeed(config *WorkflowConfig, nodeInfoList []NodeInfo, seed int64, keep []NodeInfo) []NodeInfo {
+	fields := we.SeedFields(config)
+	if len(fields) == 0 {
+		logWarn("工作流没有随机种子字段，忽略 seed", "workflowId", config.ID, "seed", seed)
+		return nodeInfoList
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, info := range keep {
+		kept[info.NodeId+"."+info.FieldName] = true
+	}
+	for _, field := range fields {
+		if kept[field.NodeId+"."+field.FieldName] {
+			continue
+		}
+		found := false
+		for i, info := range nodeInfoList {
+			if info.NodeId == field.NodeId && info.FieldName == field.FieldName {
+				nodeInfoList[i].FieldValue = seed
+				found = true
+			}
+		}
+		if !found {
+			nodeInfoList = append(nodeInfoList, NodeInfo{NodeId: field.NodeId, FieldName: field.FieldName, FieldValue: seed})
+		}
+	}
+	return nodeInfoList
+}
+
+// TaskSeed 返回节点参数中的随机种子，没有种子字段或种子不是整数时返回 nil
+func (we *WorkflowExecutor) TaskSeed(workflowID string, nodeInfoList []NodeInfo) *int64 {
+	config, _ := we.manager.GetWorkflow(workflowID)
+	for _, info := range nodeInfoList {
+		if !config.isSeedField(info.NodeId, info.FieldName) {
+			continue
+		}
+		var seed int64
+		switch v := info.FieldValue.(type) {
+		case int64:
+			seed = v
+		case int:
+			seed = int64(v)
+		case float64:
+			seed = int64(v)
+		default:
+			n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+			if err != nil {
+				continue
+			}
+			seed = n
+		}
+		return &seed
+	}
+	return nil
+}
+
+// OutputBaseName 返回结果文件名前缀：baseName 为空时使用任务ID，有随机种子时追加 _seed<种子>
+func OutputBaseName(baseName, taskID string, seed *int64) string {
+	if baseName == "" {
+		baseName = taskID
+	}
+	if seed != nil {
+		baseName += fmt.Sprintf("_seed%d", *seed)
+	}
+	return baseName
+}
+
+// RerunTask 用任务记录中的节点参数重新提交任务，得到与原任务相同的输入和种子
+// 原任务由 Key 池中的某个账户创建时固定使用该账户（参数中已上传的文件属于该账户），需先用 RememberTaskKey 记录该账户的 Key
+func RerunTask(record *TaskRecord) (*TaskCreateResponse, error) {
+	if record.WorkflowID == "" || len(record.NodeInfoList) == 0 {
+		return nil, fmt.Errorf("任务记录中没有工作流ID或节点参数，无法重新提交: %s", record.TaskID)
+	}
+	pinned := ""
+	if record.Profile != "" {
+		pinned = taskApiKey(record.TaskID)
+	}
+	return withApiKey(pinned, func(apiKey string) (*TaskCreateResponse, error) {
+		return createAdvancedTask(apiKey, record.WorkflowID, record.NodeInfoList)
+	})
+}
@@ -0,0 +1,605 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jobQueueSize 排队作业数量上限，超过时提交接口返回 503
+const jobQueueSize = 4096
+
+// maxUploadSize 通过 multipart 上传的输入文件总大小上限
+const maxUploadSize = 512 << 20
+
+// EnvServeToken 服务模式的访问令牌，未指定 serve -token 时使用
+const EnvServeToken = "RUNNINGHUB_SERVE_TOKEN"
+
+// Server 服务模式，通过本地 HTTP 接口提交和管理作业
+//
+// 接口列表:
+//   - POST /api/jobs: 提交作业，JSON 对象或 multipart 表单，字段与清单的一行相同（workflow、image、text、<节点ID>.<字段名> 等），
+//     multipart 中的文件字段（image、video、audio）会保存到作业目录后作为输入；
+//     以路径指定的输入文件只能位于 SetInputRoot 设置的目录下，未设置时只接受 multipart 上传的文件
+//   - GET /api/jobs[?status=]: 列出作业
+//   - GET /api/jobs/<ID>: 查询作业状态和输出
+//   - GET /api/jobs/<ID>/files/<N>: 下载作业的第 N 个输出文件
+//   - POST /api/jobs/<ID>/cancel 或 DELETE /api/jobs/<ID>: 取消作业
+//   - GET /api/workflows: 列出工作流
+//   - GET /api/account: 查询账户状态
+//
+// 设置了 SetToken 时所有接口都需要 Authorization: Bearer <令牌> 请求头
+//
+// 执行器被中断（WorkflowExecutor.Interrupt）后 ListenAndServe 停止接受请求，执行中的作业按中断策略处理：
+// 取消的作业结束，停止等待和尚未开始的作业保留为未完成状态，服务重启后继续执行
+type Server struct {
+	executor    *WorkflowExecutor
+	store       *JobStore
+	concurrency int
+	queue       chan string
+	inputRoot   string // 允许以路径指定的输入文件所在的目录（已解析符号链接的绝对路径），为空时不接受路径
+	token       string // 访问令牌，为空时不校验
+	workers     sync.WaitGroup
+}
+
+// NewServer 创建服务，concurrency 为同时执行的作业数
+func NewServer(executor *WorkflowExecutor, store *JobStore, concurrency int) *Server {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Server{
+		executor:    executor,
+		store:       store,
+		concurrency: concurrency,
+		queue:       make(chan string, jobQueueSize),
+	}
+}
+
+// SetInputRoot 允许 JSON 等请求以路径指定 dir 下的输入文件，相对路径以 dir 为基准；为空时输入文件只能通过 multipart 上传
+func (s *Server) SetInputRoot(dir string) error {
+	if dir == "" {
+		s.inputRoot = ""
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return fmt.Errorf("输入目录无效: %v", err)
+	}
+	s.inputRoot = abs
+	return nil
+}
+
+// SetToken 设置访问令牌，为空时不校验
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// Handler 返回服务的 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/jobs/", s.handleJob)
+	mux.HandleFunc("/api/workflows", s.handleWorkflows)
+	mux.HandleFunc("/api/account", s.handleAccount)
+	if s.token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("缺少或错误的访问令牌"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized 校验请求的 Authorization: Bearer 令牌
+func (s *Server) authorized(r *http.Request) bool {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.token)) == 1
+}
+
+// Start 启动工作协程，并重新排队上次服务退出时未结束的作业
+func (s *Server) Start() {
+	s.workers.Add(s.concurrency)
+	for i := 0; i < s.concurrency; i++ {
+		go s.worker()
+	}
+	for _, job := range s.store.List("") {
+		if !job.Finished() {
+			logInfo("[服务] 恢复未完成的作业", "id", job.ID, "status", job.Status)
+			s.queue <- job.ID
+		}
+	}
+}
+
+// ListenAndServe 启动工作协程并在 addr 上提供 HTTP 接口，直到出错或执行器被中断
+// 中断后关闭 HTTP 服务，等待执行中的作业按中断策略结束后返回 nil
+func (s *Server) ListenAndServe(addr string) error {
+	s.Start()
+	logInfo("[服务] 开始监听", "addr", addr, "concurrency", s.concurrency, "jobs", s.store.Dir(), "inputRoot", s.inputRoot, "token", s.token != "")
+	if s.token == "" && !loopbackAddr(addr) {
+		logWarn("[服务] 监听地址不是本机地址且未设置访问令牌，任何能访问该地址的人都可以提交作业", "addr", addr)
+	}
+
+	server := &http.Server{Addr: addr, Handler: s.Handler()}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-s.executor.interrupted():
+	}
+
+	logInfo("[服务] 停止接受请求，等待执行中的作业结束")
+	if err := server.Shutdown(context.Background()); err != nil {
+		logError("[服务] 关闭 HTTP 服务失败", "error", err)
+	}
+	s.workers.Wait()
+	logInfo("[服务] 已退出，未完成的作业将在服务重启后继续执行")
+	return nil
+}
+
+// worker 依次执行队列中的作业，执行器被中断后不再取新的作业
+func (s *Server) worker() {
+	defer s.workers.Done()
+	for {
+		select {
+		case <-s.executor.interrupted():
+			return
+		case id := <-s.queue:
+			if s.executor.Interrupted() {
+				return
+			}
+			s.runJob(id)
+		}
+	}
+}
+
+// runJob 执行一个作业，作业已取消或已结束时直接跳过
+func (s *Server) runJob(id string) {
+	job, err := s.store.Update(id, func(job *Job) {
+		if !job.Finished() {
+			job.Status = JobRunning
+		}
+	})
+	if err != nil || job.Status != JobRunning {
+		return
+	}
+	finish := func(result *TaskResult) {
+		s.store.Update(id, func(job *Job) {
+			job.Result = result
+			job.Status = result.Status
+			switch {
+			case job.Canceling:
+				job.Status = JobCanceled
+			case result.Status == TaskStatusSkipped:
+				// 服务被中断时尚未提交，重启后重新排队
+				job.Status = JobQueued
+			case result.Status == TaskStatusDetached:
+				// 服务被中断时停止等待，任务仍在服务器上执行，重启后继续等待
+				job.Status = JobRunning
+			}
+		})
+		logInfo("[服务] 作业结束", "id", id, "status", result.Status)
+	}
+
+	mj, err := job.manifestJob()
+	if err != nil {
+		finish(&TaskResult{Label: id, WorkflowID: job.WorkflowID, Status: TaskStatusError, Error: err.Error()})
+		return
+	}
+
+	// 服务重启前任务已创建时，继续等待原任务而不是重新提交
+	create := func() (*TaskCreateResponse, error) {
+		return s.executor.ExecuteWorkflowWithInputs(mj.WorkflowID, mj.Inputs)
+	}
+	if job.Result != nil && job.Result.TaskID != "" {
+		previous := *job.Result
+		create = func() (*TaskCreateResponse, error) {
+			resp := &TaskCreateResponse{WorkflowId: previous.WorkflowID, NodeInfoList: previous.NodeInfoList, Profile: previous.Profile}
+			resp.Data.TaskId = previous.TaskID
+			restoreTaskKey(previous.TaskID, previous.Profile)
+			return resp, nil
+		}
+	}
+
+	result := runTask(taskJob{
+		tag:       "[服务]",
+		label:     id,
+		outputDir: filepath.Join(CreateOutputDir(), "jobs"),
+		baseName:  mj.BaseName(),
+		inputs:    mj.inputFiles(),
+		create:    create,
+		onCreated: func(result *TaskResult) {
+			snapshot := *result
+			job, err := s.store.Update(id, func(job *Job) { job.Result = &snapshot })
+			if err == nil && job.Canceling {
+				s.cancelTask(id, snapshot.TaskID)
+			}
+		},
+		canceled: func() bool {
+			job, ok := s.store.Get(id)
+			return ok && job.Canceling
+		},
+	}, s.executor)
+	finish(result)
+}
+
+// cancelTask 取消服务器上的任务
+func (s *Server) cancelTask(id, taskID string) {
+	resp, err := CancelTask(taskID)
+	if err != nil {
+		logError("[服务] 取消任务失败", "id", id, "taskId", taskID, "error", err)
+		return
+	}
+	logInfo("[服务] 已请求取消任务", "id", id, "taskId", taskID, "code", resp.Code, "msg", resp.Msg)
+}
+
+// handleJobs 处理 /api/jobs
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.store.List(r.URL.Query().Get("status")))
+	case http.MethodPost:
+		s.submitJob(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+	}
+}
+
+// submitJob 校验并提交作业
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	request, uploaded, err := s.parseJobRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.resolveInputPaths(request, uploaded); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	mj, err := manifestRow{fields: request}.toJob("", "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, exists := s.executor.manager.GetWorkflow(mj.WorkflowID); !exists {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("工作流不存在: %s", mj.WorkflowID))
+		return
+	}
+
+	job, err := s.store.Create(mj.WorkflowID, request)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	select {
+	case s.queue <- job.ID:
+	default:
+		s.store.Update(job.ID, func(job *Job) {
+			job.Status = TaskStatusError
+			job.Result = &TaskResult{Label: job.ID, WorkflowID: job.WorkflowID, Status: TaskStatusError, Error: "作业队列已满"}
+		})
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("作业队列已满"))
+		return
+	}
+	logInfo("[服务] 作业已提交", "id", job.ID, "workflowId", job.WorkflowID)
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// parseJobRequest 解析 JSON 或 multipart 请求体，multipart 中的文件保存到作业目录下的 uploads/，
+// 同时返回由上传文件设置的字段
+func (s *Server) parseJobRequest(r *http.Request) (map[string]interface{}, map[string]bool, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		decoder.UseNumber()
+		var request map[string]interface{}
+		if err := decoder.Decode(&request); err != nil {
+			return nil, nil, fmt.Errorf("解析请求失败: %v", err)
+		}
+		return request, nil, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, nil, fmt.Errorf("解析表单失败: %v", err)
+	}
+	request := make(map[string]interface{})
+	for key, values := range r.MultipartForm.Value {
+		if len(values) > 0 {
+			request[key] = values[0]
+		}
+	}
+	uploaded := make(map[string]bool)
+	if len(r.MultipartForm.File) == 0 {
+		return request, uploaded, nil
+	}
+	dir := filepath.Join(s.store.Dir(), "uploads", newJobID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("创建上传目录失败: %v", err)
+	}
+	for key, headers := range r.MultipartForm.File {
+		if len(headers) == 0 {
+			continue
+		}
+		switch key {
+		case "image", "video", "audio":
+		default:
+			return nil, nil, fmt.Errorf("不支持的文件字段: %s", key)
+		}
+		path := filepath.Join(dir, uploadFileName(key, headers[0].Filename))
+		if err := saveUploadedFile(headers[0], path); err != nil {
+			return nil, nil, err
+		}
+		request[key] = path
+		uploaded[key] = true
+	}
+	return request, uploaded, nil
+}
+
+// resolveInputPaths 检查请求中以路径指定的输入文件（image、video、audio 和文件输入节点的字段覆盖，上传的文件除外）
+// 都位于 inputRoot 下，并替换为绝对路径；未设置 inputRoot 时拒绝以路径指定的输入文件
+func (s *Server) resolveInputPaths(request map[string]interface{}, uploaded map[string]bool) error {
+	var config *WorkflowConfig
+	for key, value := range request {
+		switch strings.ToLower(key) {
+		case "workflow", "workflowid", "workflow_id":
+			config, _ = s.executor.manager.GetWorkflow(strings.TrimSpace(fmt.Sprint(value)))
+		}
+	}
+	for key, value := range request {
+		str := strings.TrimSpace(fmt.Sprint(value))
+		if uploaded[key] || str == "" || !isFileField(config, key) {
+			continue
+		}
+		if s.inputRoot == "" {
+			return fmt.Errorf("%s: 服务未设置输入目录，输入文件请通过 multipart 上传", key)
+		}
+		path, err := s.inputPath(str)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		request[key] = path
+	}
+	return nil
+}
+
+// inputPath 返回 inputRoot 下的输入文件的绝对路径，文件不在 inputRoot 下（包括经符号链接指向外部）时返回错误
+func (s *Server) inputPath(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.inputRoot, p)
+	}
+	p = filepath.Clean(p)
+	// 先按字面路径检查，避免通过错误信息探测输入目录之外的文件
+	if !withinDir(s.inputRoot, p) {
+		return "", fmt.Errorf("输入文件不在服务的输入目录下: %s", p)
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("输入文件不存在: %s", p)
+	}
+	if !withinDir(s.inputRoot, resolved) {
+		return "", fmt.Errorf("输入文件不在服务的输入目录下: %s", p)
+	}
+	return resolved, nil
+}
+
+// withinDir 判断 path 是否位于 dir 下，两者都是清理过的绝对路径
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isFileField 判断请求字段的值是否为本地文件路径：image、video、audio，或工作流中文件输入节点的 <节点ID>.<字段名>
+func isFileField(config *WorkflowConfig, key string) bool {
+	switch strings.ToLower(key) {
+	case "image", "video", "audio":
+		return true
+	}
+	nodeID, fieldName, ok := strings.Cut(key, ".")
+	if !ok || config == nil {
+		return false
+	}
+	for _, param := range config.Params {
+		if param.NodeId == nodeID && param.FieldName == fieldName {
+			return param.InputKind() != ""
+		}
+	}
+	return false
+}
+
+// loopbackAddr 判断监听地址是否只接受本机连接
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// uploadFileName 生成保存上传文件使用的文件名：字段名加随机串，只保留客户端文件名中的扩展名（小写字母和数字）
+func uploadFileName(key, clientName string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	name := key + "_" + hex.EncodeToString(b)
+	ext := strings.ToLower(filepath.Ext(clientName))
+	if len(ext) < 2 || len(ext) > 10 {
+		return name
+	}
+	for _, c := range ext[1:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return name
+		}
+	}
+	return name + ext
+}
+
+// saveUploadedFile 保存上传的文件
+func saveUploadedFile(header *multipart.FileHeader, path string) error {
+	src, err := header.Open()
+	if err != nil {
+		return fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("保存上传文件失败: %v", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("保存上传文件失败: %v", err)
+	}
+	return dst.Close()
+}
+
+// handleJob 处理 /api/jobs/<ID>、/api/jobs/<ID>/cancel 和 /api/jobs/<ID>/files/<N>
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+	id := parts[0]
+	job, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("作业不存在: %s", id))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, job)
+	case len(parts) == 1 && r.Method == http.MethodDelete,
+		len(parts) == 2 && parts[1] == "cancel" && r.Method == http.MethodPost:
+		s.cancelJob(w, id)
+	case len(parts) == 3 && parts[1] == "files" && r.Method == http.MethodGet:
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || job.Result == nil || n < 0 || n >= len(job.Result.Files) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("输出文件不存在: %s", parts[2]))
+			return
+		}
+		http.ServeFile(w, r, job.Result.Files[n])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("未知的接口: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// cancelJob 取消作业：排队中的作业直接取消，执行中的作业请求服务器取消任务
+func (s *Server) cancelJob(w http.ResponseWriter, id string) {
+	taskID, canceled := "", false
+	job, err := s.store.Update(id, func(job *Job) {
+		switch job.Status {
+		case JobQueued:
+			job.Status = JobCanceled
+			canceled = true
+		case JobRunning:
+			job.Canceling = true
+			canceled = true
+			if job.Result != nil {
+				taskID = job.Result.TaskID
+			}
+		}
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !canceled {
+		writeError(w, http.StatusConflict, fmt.Errorf("作业已结束: %s", job.Status))
+		return
+	}
+	// 任务尚未创建时，由 runJob 在任务创建后取消
+	if taskID != "" {
+		s.cancelTask(id, taskID)
+	}
+	logInfo("[服务] 作业取消", "id", id, "status", job.Status)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleWorkflows 处理 /api/workflows
+func (s *Server) handleWorkflows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.executor.manager.ListWorkflows())
+}
+
+// handleAccount 处理 /api/account
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	pool := GetKeyPool()
+	if pool == nil {
+		status, err := GetAccountStatus(GetApiKey())
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status.Data)
+		return
+	}
+
+	// 使用 Key 池时返回每个账户的状态
+	type accountEntry struct {
+		Profile string      `json:"profile"`
+		Status  interface{} `json:"status,omitempty"`
+		Error   string      `json:"error,omitempty"`
+	}
+	var accounts []accountEntry
+	for _, p := range pool.Profiles() {
+		entry := accountEntry{Profile: p.Name}
+		status, err := GetAccountStatus(p.ApiKey)
+		switch {
+		case err != nil:
+			entry.Error = err.Error()
+		case status.Code != 0:
+			entry.Error = fmt.Sprintf("code: %d, msg: %s", status.Code, status.Msg)
+		default:
+			entry.Status = status.Data
+		}
+		accounts = append(accounts, entry)
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		logWarn("[服务] 写入响应失败", "error", err)
+	}
+}
+
+// writeError 输出 {"error": "..."} 格式的错误响应
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
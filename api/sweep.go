@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SweepParam 参数扫描中的一个节点字段及其候选值
+type SweepParam struct {
+	NodeId    string        `json:"nodeId"`    // 节点ID
+	FieldName string        `json:"fieldName"` // 字段名
+	Values    []interface{} `json:"values"`    // 候选值
+}
+
+// Key 返回 <节点ID>.<字段名> 形式的字段标识
+func (p SweepParam) Key() string {
+	return p.NodeId + "." + p.FieldName
+}
+
+// SweepSpec 参数扫描配置
+// 对 Params 中所有字段的候选值做笛卡尔积，每个组合提交一个任务
+type SweepSpec struct {
+	WorkflowID string                 `json:"workflow"`         // 工作流ID
+	Name       string                 `json:"name"`             // 名称，用作输出目录和文件名前缀
+	Image      string                 `json:"image,omitempty"`  // 图片输入，所有组合共用
+	Video      string                 `json:"video,omitempty"`  // 视频输入，所有组合共用
+	Audio      string                 `json:"audio,omitempty"`  // 音频输入，所有组合共用
+	Text       string                 `json:"text,omitempty"`   // 文本提示词，所有组合共用
+	Fields     map[string]interface{} `json:"fields,omitempty"` // 所有组合共用的固定字段，key 为 <节点ID>.<字段名>
+	Params     []SweepParam           `json:"params"`           // 扫描的字段
+}
+
+// SweepCombination 参数组合
+type SweepCombination struct {
+	Index  int        `json:"index"`  // 组合序号，从 1 开始
+	Values []NodeInfo `json:"values"` // 该组合中各字段的取值
+	Tag    string     `json:"tag"`    // 用于文件名的组合标签
+}
+
+// SweepEntry 参数扫描中一个组合的执行结果
+type SweepEntry struct {
+	SweepCombination
+	Result *TaskResult `json:"result"`
+}
+
+// SweepIndex 参数扫描的对比索引
+type SweepIndex struct {
+	Spec     *SweepSpec   `json:"spec"`
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Entries  []SweepEntry `json:"entries"`
+	Run      *RunRecord   `json:"-"` // 本次扫描的运行记录
+}
+
+// LoadSweepSpec 读取 JSON 格式的参数扫描配置，相对路径以配置文件所在目录为基准
+func LoadSweepSpec(specPath string) (*SweepSpec, error) {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取扫描配置失败: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	var spec SweepSpec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("解析扫描配置失败: %v", err)
+	}
+
+	if len(spec.Params) == 0 {
+		return nil, fmt.Errorf("扫描配置中没有 params")
+	}
+	for _, p := range spec.Params {
+		if p.NodeId == "" || p.FieldName == "" {
+			return nil, fmt.Errorf("扫描字段缺少 nodeId 或 fieldName")
+		}
+		if len(p.Values) == 0 {
+			return nil, fmt.Errorf("扫描字段 %s 没有候选值", p.Key())
+		}
+	}
+	if spec.Name == "" {
+		base := filepath.Base(specPath)
+		spec.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	baseDir := filepath.Dir(specPath)
+	for _, p := range []*string{&spec.Image, &spec.Video, &spec.Audio} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(baseDir, *p)
+		}
+	}
+	return &spec, nil
+}
+
+// Combinations 返回所有参数组合，靠后的字段变化最快
+func (s *SweepSpec) Combinations() []SweepCombination {
+	combos := [][]NodeInfo{nil}
+	for _, p := range s.Params {
+		next := make([][]NodeInfo, 0, len(combos)*len(p.Values))
+		for _, combo := range combos {
+			for _, v := range p.Values {
+				values := append(append([]NodeInfo{}, combo...), NodeInfo{
+					NodeId:     p.NodeId,
+					FieldName:  p.FieldName,
+					FieldValue: v,
+				})
+				next = append(next, values)
+			}
+		}
+		combos = next
+	}
+
+	result := make([]SweepCombination, len(combos))
+	for i, values := range combos {
+		result[i] = SweepCombination{
+			Index:  i + 1,
+			Values: values,
+			Tag:    s.combinationTag(values),
+		}
+	}
+	return result
+}
+
+// unsafeTagChars 文件名标签中不允许的字符
+var unsafeTagChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// combinationTag 生成组合的文件名标签
+// 较短的值直接写入标签，如 seed-42；较长的值（如提示词）使用候选值序号，如 text-v2
+func (s *SweepSpec) combinationTag(values []NodeInfo) string {
+	parts := make([]string, 0, len(values))
+	for i, v := range values {
+		value := fmt.Sprint(v.FieldValue)
+		if len(value) > 16 || unsafeTagChars.MatchString(value) {
+			value = fmt.Sprintf("v%d", valueIndex(s.Params[i].Values, v.FieldValue)+1)
+		}
+		parts = append(parts, unsafeTagChars.ReplaceAllString(v.FieldName, "")+"-"+value)
+	}
+	return strings.Join(parts, "_")
+}
+
+func valueIndex(values []interface{}, v interface{}) int {
+	for i, candidate := range values {
+		if fmt.Sprint(candidate) == fmt.Sprint(v) {
+			return i
+		}
+	}
+	return 0
+}
+
+// inputs 返回所有组合共用的任务输入
+func (s *SweepSpec) inputs() (TaskInputs, error) {
+	inputs := TaskInputs{
+		Files: make(map[InputKind]string),
+		Text:  s.Text,
+	}
+	for kind, p := range map[InputKind]string{InputImage: s.Image, InputVideo: s.Video, InputAudio: s.Audio} {
+		if p != "" {
+			inputs.Files[kind] = p
+		}
+	}
+	for key, value := range s.Fields {
+		nodeID, fieldName, ok := strings.Cut(key, ".")
+		if !ok || nodeID == "" || fieldName == "" {
+			return inputs, fmt.Errorf("无法识别的固定字段: %s（请使用 <节点ID>.<字段名>）", key)
+		}
+		inputs.Overrides = append(inputs.Overrides, NodeInfo{NodeId: nodeID, FieldName: fieldName, FieldValue: value})
+	}
+	sortNodeInfos(inputs.Overrides)
+	return inputs, nil
+}
+
+// RunSweep 提交所有参数组合的任务，结果和对比索引保存到 outputs/<日期>/sweep_<名称>_<时间>/ 目录
+func RunSweep(spec *SweepSpec, concurrency int, executor *WorkflowExecutor) (*SweepIndex, error) {
+	if _, exists := executor.manager.GetWorkflow(spec.WorkflowID); !exists {
+		return nil, fmt.Errorf("工作流不存在: %s", spec.WorkflowID)
+	}
+	base, err := spec.inputs()
+	if err != nil {
+		return nil, err
+	}
+
+	// 输入文件只上传一次，所有组合共用，使用 Key 池时所有组合由上传文件的账户创建
+	if base.ApiKey, err = acquireUploadKey(); err != nil {
+		return nil, err
+	}
+	base.Uploaded = make(map[InputKind]string)
+	for kind, p := range base.Files {
+		uploadResp, err := uploadFile(base.ApiKey, p, uploadFileType(kind))
+		if err != nil {
+			return nil, fmt.Errorf("上传文件失败: %v", err)
+		}
+		if uploadResp.Code != 0 {
+			return nil, fmt.Errorf("上传文件失败: %s, code: %d, msg: %s", p, uploadResp.Code, uploadResp.Msg)
+		}
+		base.Uploaded[kind] = uploadResp.Data.FileName
+	}
+
+	combos := spec.Combinations()
+	sweepDir := filepath.Join(CreateOutputDir(), fmt.Sprintf("sweep_%s_%s", unsafeTagChars.ReplaceAllString(spec.Name, "_"), time.Now().Format("20060102_150405")))
+	if err := os.MkdirAll(sweepDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建扫描目录失败: %v", err)
+	}
+	logInfo("[扫描] 开始参数扫描", "workflowId", spec.WorkflowID, "combinations", len(combos), "dir", sweepDir)
+
+	run := newRunRecord(RunKindSweep, spec.WorkflowID, sweepDir, len(combos))
+	run.start(executor)
+	index := &SweepIndex{Spec: spec, Started: run.Started, Entries: make([]SweepEntry, len(combos)), Run: run}
+	var inputFiles []string
+	for _, p := range []string{spec.Image, spec.Video, spec.Audio} {
+		if p != "" {
+			inputFiles = append(inputFiles, p)
+		}
+	}
+	runConcurrent(concurrency, len(combos), func(i int) {
+		combo := combos[i]
+		inputs := base
+		inputs.Overrides = append(append([]NodeInfo{}, base.Overrides...), combo.Values...)
+		result := runTask(taskJob{
+			tag:       "[扫描]",
+			label:     fmt.Sprintf("组合 %d/%d(%s)", combo.Index, len(combos), combo.Tag),
+			outputDir: sweepDir,
+			baseName:  fmt.Sprintf("c%03d_%s", combo.Index, combo.Tag),
+			inputs:    inputFiles,
+			create: func() (*TaskCreateResponse, error) {
+				return executor.ExecuteWorkflowWithInputs(spec.WorkflowID, inputs)
+			},
+		}, executor)
+		index.Entries[i] = SweepEntry{SweepCombination: combo, Result: result}
+		run.Tasks[i] = result
+	})
+	index.Finished = time.Now()
+	run.finish(executor)
+
+	if err := index.Write(sweepDir); err != nil {
+		return index, err
+	}
+	logInfo("参数扫描完成", "dir", sweepDir)
+	return index, nil
+}
+
+// Write 将对比索引写入 dir 下的 index.json 和 index.md
+func (idx *SweepIndex) Write(dir string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化扫描索引失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), data, 0644); err != nil {
+		return fmt.Errorf("写入扫描索引失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.md"), []byte(idx.Markdown(dir)), 0644); err != nil {
+		return fmt.Errorf("写入扫描索引失败: %v", err)
+	}
+	return nil
+}
+
+// Markdown 生成对比表格，每行一个组合，文件路径相对 dir
+func (idx *SweepIndex) Markdown(dir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 参数扫描: %s\n\n", idx.Spec.Name)
+	fmt.Fprintf(&b, "- 工作流: `%s`\n", idx.Spec.WorkflowID)
+	fmt.Fprintf(&b, "- 组合数: %d\n", len(idx.Entries))
+	fmt.Fprintf(&b, "- 开始时间: %s\n", idx.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 总耗时: %s\n\n", idx.Finished.Sub(idx.Started).Round(time.Second))
+
+	b.WriteString("| # |")
+	for _, p := range idx.Spec.Params {
+		fmt.Fprintf(&b, " %s |", p.Key())
+	}
+	b.WriteString(" 状态 | 任务ID | 耗时 | 结果 |\n|---|")
+	for range idx.Spec.Params {
+		b.WriteString("---|")
+	}
+	b.WriteString("---|---|---|---|\n")
+
+	for _, e := range idx.Entries {
+		fmt.Fprintf(&b, "| %d |", e.Index)
+		for _, v := range e.Values {
+			fmt.Fprintf(&b, " %s |", markdownCell(fmt.Sprint(v.FieldValue)))
+		}
+		if e.Result == nil {
+			b.WriteString(" - | - | - | - |\n")
+			continue
+		}
+		links := make([]string, 0, len(e.Result.Files))
+		for _, f := range e.Result.Files {
+			rel, err := filepath.Rel(dir, f)
+			if err != nil {
+				rel = f
+			}
+			links = append(links, fmt.Sprintf("[%s](%s)", filepath.Base(f), filepath.ToSlash(rel)))
+		}
+		fmt.Fprintf(&b, " %s | %s | %s | %s |\n", e.Result.Status, e.Result.TaskID,
+			e.Result.Duration.Round(time.Second), strings.Join(links, "<br>"))
+	}
+	return b.String()
+}
+
+// markdownCell 转义表格单元格中的特殊字符
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type NodeInfo struct {
@@ -24,6 +26,11 @@ type TaskCreateResponse struct {
 		TaskStatus string `json:"taskStatus"`
 		PromptTips string `json:"promptTips"`
 	} `json:"data"`
+
+	// 以下为本地记录的请求参数，不来自接口响应
+	WorkflowId   string     `json:"-"` // 请求的工作流ID
+	NodeInfoList []NodeInfo `json:"-"` // 请求的节点参数列表
+	Profile      string     `json:"-"` // 创建任务的账户，使用 Key 池时设置
 }
 
 type TaskStatusResponse struct {
@@ -37,6 +44,30 @@ type TaskOutput struct {
 	FileType     string `json:"fileType"`
 	TaskCostTime string `json:"taskCostTime"`
 	NodeId       string `json:"nodeId"`
+	ConsumeCoins Coins  `json:"consumeCoins,omitempty"`
+}
+
+// Coins 金币数量，兼容接口返回的字符串和数字
+type Coins float64
+
+// UnmarshalJSON 解析字符串或数字形式的金币数量，空值解析为 0
+func (c *Coins) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*c = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("无效的金币数量: %s", data)
+	}
+	*c = Coins(v)
+	return nil
+}
+
+// String 格式化金币数量，去掉多余的小数位
+func (c Coins) String() string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", float64(c)), "0"), ".")
 }
 
 type TaskOutputResponse struct {
@@ -51,15 +82,22 @@ type CancelTaskResponse struct {
 	Data interface{} `json:"data"`
 }
 
-// CreateAdvancedTask 发起高级 ComfyUI 任务
+// CreateAdvancedTask 发起高级 ComfyUI 任务，设置了 Key 池时由池选择账户
 // workflowId: 工作流ID
 // nodeInfoList: 节点参数修改列表
 func CreateAdvancedTask(workflowId string, nodeInfoList []NodeInfo) (*TaskCreateResponse, error) {
+	return withApiKey("", func(apiKey string) (*TaskCreateResponse, error) {
+		return createAdvancedTask(apiKey, workflowId, nodeInfoList)
+	})
+}
+
+// createAdvancedTask 使用指定的 API Key 发起任务
+func createAdvancedTask(apiKey, workflowId string, nodeInfoList []NodeInfo) (*TaskCreateResponse, error) {
 	url := "https://www.runninghub.cn/task/openapi/create"
 	method := "POST"
 
 	payload := map[string]interface{}{
-		"apiKey":       ApiKey,
+		"apiKey":       apiKey,
 		"workflowId":   workflowId,
 		"nodeInfoList": nodeInfoList,
 	}
@@ -69,9 +107,7 @@ func CreateAdvancedTask(workflowId string, nodeInfoList []NodeInfo) (*TaskCreate
 		return nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	// 打印请求参数
-	fmt.Println("[CreateAdvancedTask] 请求URL:", url)
-	fmt.Println("[CreateAdvancedTask] 请求参数:", string(jsonData))
+	logDebug("[CreateAdvancedTask] 发送请求", "url", url, "payload", jsonData)
 
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonData))
 	if err != nil {
@@ -93,13 +129,14 @@ func CreateAdvancedTask(workflowId string, nodeInfoList []NodeInfo) (*TaskCreate
 		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
 
-	// 打印响应内容
-	fmt.Println("[CreateAdvancedTask] 响应内容:", string(body))
+	logDebug("[CreateAdvancedTask] 响应内容", "body", body)
 
 	var taskResp TaskCreateResponse
 	if err := json.Unmarshal(body, &taskResp); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	taskResp.WorkflowId = workflowId
+	taskResp.NodeInfoList = nodeInfoList
 
 	return &taskResp, nil
 }
@@ -111,7 +148,7 @@ func QueryTaskStatus(taskId string) (*TaskStatusResponse, error) {
 	method := "POST"
 
 	payload := map[string]string{
-		"apiKey": ApiKey,
+		"apiKey": taskApiKey(taskId),
 		"taskId": taskId,
 	}
 
@@ -155,7 +192,7 @@ func QueryTaskOutputs(taskId string) (*TaskOutputResponse, error) {
 	method := "POST"
 
 	payload := map[string]string{
-		"apiKey": ApiKey,
+		"apiKey": taskApiKey(taskId),
 		"taskId": taskId,
 	}
 
@@ -199,7 +236,7 @@ func CancelTask(taskId string) (*CancelTaskResponse, error) {
 	method := "POST"
 
 	payload := map[string]string{
-		"apiKey": ApiKey,
+		"apiKey": taskApiKey(taskId),
 		"taskId": taskId,
 	}
 
@@ -235,3 +272,85 @@ func CancelTask(taskId string) (*CancelTaskResponse, error) {
 
 	return &cancelResp, nil
 }
+
+// WorkflowNode API 格式工作流中的一个节点
+type WorkflowNode struct {
+	ClassType string                 `json:"class_type"`
+	Inputs    map[string]interface{} `json:"inputs"` // 字段名 -> 值，连接到其他节点的字段为 [节点ID, 输出序号]
+}
+
+// WorkflowJSONResponse 获取工作流 JSON 的响应
+type WorkflowJSONResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Prompt string `json:"prompt"` // API 格式的工作流 JSON: 节点ID -> 节点
+	} `json:"data"`
+}
+
+// GetWorkflowJSON 获取工作流的 API 格式 JSON，返回节点ID到节点的映射
+// 使用 Key 池时依次用池中各账户的 Key 请求，直到有账户可以访问该工作流
+// workflowId: 工作流ID
+func GetWorkflowJSON(workflowId string) (map[string]WorkflowNode, error) {
+	var lastErr error
+	for _, apiKey := range queryApiKeys() {
+		nodes, err := getWorkflowJSON(apiKey, workflowId)
+		if err == nil {
+			return nodes, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// getWorkflowJSON 使用 apiKey 获取工作流的 API 格式 JSON
+func getWorkflowJSON(apiKey, workflowId string) (map[string]WorkflowNode, error) {
+	url := "https://www.runninghub.cn/api/openapi/getJsonApiFormat"
+	method := "POST"
+
+	payload := map[string]string{
+		"apiKey":     apiKey,
+		"workflowId": workflowId,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	req.Header.Set("Host", "www.runninghub.cn")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var workflowResp WorkflowJSONResponse
+	if err := json.Unmarshal(body, &workflowResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if workflowResp.Code != 0 {
+		return nil, fmt.Errorf("code: %d, msg: %s", workflowResp.Code, workflowResp.Msg)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(workflowResp.Data.Prompt))
+	decoder.UseNumber()
+	var nodes map[string]WorkflowNode
+	if err := decoder.Decode(&nodes); err != nil {
+		return nil, fmt.Errorf("解析工作流 JSON 失败: %v", err)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 文本拆分方式
+const (
+	SplitLine      = "line"      // 按行拆分
+	SplitParagraph = "paragraph" // 按空行分隔的段落拆分
+	SplitSentence  = "sentence"  // 按句子拆分
+	SplitChunk     = "chunk"     // 按句子合并为不超过最大字符数的块
+)
+
+// TextPlaceholder 提示词模板中的文本占位符
+const TextPlaceholder = "{text}"
+
+// TextOptions 批量文本处理选项
+type TextOptions struct {
+	File        string // 文本文件路径，默认 doc/book.txt
+	Split       string // 拆分方式: line, paragraph, sentence, chunk
+	MaxChars    int    // chunk 模式下每块的最大字符数
+	Template    string // 提示词模板，{text} 会被替换为每段文本
+	Concurrency int    // 并发数量
+}
+
+// DefaultTextOptions 返回默认的批量文本处理选项
+func DefaultTextOptions() TextOptions {
+	return TextOptions{
+		File:        "doc/book.txt",
+		Split:       SplitLine,
+		MaxChars:    500,
+		Template:    TextPlaceholder,
+		Concurrency: 1,
+	}
+}
+
+// SplitText 按指定方式拆分文本，返回去掉首尾空白后的非空片段
+func SplitText(content, mode string, maxChars int) ([]string, error) {
+	// 统一换行符为 \n
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+
+	var parts []string
+	switch mode {
+	case "", SplitLine:
+		parts = strings.Split(content, "\n")
+	case SplitParagraph:
+		parts = splitParagraphs(content)
+	case SplitSentence:
+		parts = splitSentences(content)
+	case SplitChunk:
+		if maxChars <= 0 {
+			return nil, fmt.Errorf("chunk 模式下最大字符数必须大于 0")
+		}
+		parts = chunkSentences(splitSentences(content), maxChars)
+	default:
+		return nil, fmt.Errorf("不支持的拆分方式: %s（支持 line、paragraph、sentence、chunk）", mode)
+	}
+
+	chunks := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			chunks = append(chunks, p)
+		}
+	}
+	return chunks, nil
+}
+
+// splitParagraphs 按空行拆分段落，段落内的换行合并为空格
+func splitParagraphs(content string) []string {
+	var paragraphs []string
+	var lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, " "))
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return paragraphs
+}
+
+// splitSentences 按中英文句末标点拆分句子，标点保留在句子末尾
+// 英文句号后需紧跟空白或结尾才视为句末，避免拆开小数和缩写
+func splitSentences(content string) []string {
+	var sentences []string
+	runes := []rune(content)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		end := false
+		switch r {
+		case '。', '！', '？', '；', '\n':
+			end = true
+		case '.', '!', '?':
+			end = i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+		}
+		if !end {
+			continue
+		}
+		// 连续的标点和右引号归入当前句子
+		for i+1 < len(runes) && strings.ContainsRune("。！？.!?”’\"')）", runes[i+1]) {
+			i++
+		}
+		sentences = append(sentences, string(runes[start:i+1]))
+		start = i + 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// chunkSentences 将句子合并为不超过 maxChars 个字符的块，超长的句子会被强制截断
+func chunkSentences(sentences []string, maxChars int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+	flush := func() {
+		if currentLen > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLen = 0
+		}
+	}
+	for _, s := range sentences {
+		s = strings.TrimSpace(s)
+		n := utf8.RuneCountInString(s)
+		if n == 0 {
+			continue
+		}
+		if n > maxChars {
+			flush()
+			runes := []rune(s)
+			for len(runes) > maxChars {
+				chunks = append(chunks, string(runes[:maxChars]))
+				runes = runes[maxChars:]
+			}
+			s, n = string(runes), len(runes)
+		}
+		// 中文句子之间不加空格
+		sep := 0
+		if currentLen > 0 {
+			last, _ := utf8.DecodeLastRuneInString(current.String())
+			first, _ := utf8.DecodeRuneInString(s)
+			if last < utf8.RuneSelf && first < utf8.RuneSelf {
+				sep = 1
+			}
+		}
+		if currentLen+sep+n > maxChars {
+			flush()
+			sep = 0
+		}
+		if sep > 0 {
+			current.WriteByte(' ')
+		}
+		current.WriteString(s)
+		currentLen += sep + n
+	}
+	flush()
+	return chunks
+}
+
+// ApplyTemplate 将文本填入提示词模板，模板为空时直接返回文本
+func ApplyTemplate(template, text string) string {
+	if template == "" {
+		return text
+	}
+	return strings.ReplaceAll(template, TextPlaceholder, text)
+}
+
+// BatchProcessText 按选项拆分文本文件，逐段作为提示词执行工作流，返回本次运行记录
+func BatchProcessText(workflowID string, opts TextOptions, executor *WorkflowExecutor) (*RunRecord, error) {
+	if _, exists := executor.manager.GetWorkflow(workflowID); !exists {
+		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
+	}
+	if opts.File == "" {
+		opts.File = DefaultTextOptions().File
+	}
+	if opts.Template != "" && !strings.Contains(opts.Template, TextPlaceholder) {
+		return nil, fmt.Errorf("提示词模板中缺少 %s 占位符: %s", TextPlaceholder, opts.Template)
+	}
+
+	logDebug("[批量文本] 读取文件", "path", opts.File)
+	content, err := os.ReadFile(opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("文件内容为空: %s", opts.File)
+	}
+
+	chunks, err := SplitText(string(content), opts.Split, opts.MaxChars)
+	if err != nil {
+		return nil, err
+	}
+	logInfo("[批量文本] 拆分文本", "path", opts.File, "split", opts.Split, "count", len(chunks))
+	for i, chunk := range chunks {
+		logDebug("[批量文本] 文本片段", "index", i+1, "length", utf8.RuneCountInString(chunk))
+	}
+	if len(chunks) == 0 {
+		logWarn("[批量文本] 文件中没有可处理的文本", "path", opts.File)
+		return nil, nil
+	}
+
+	run := newRunRecord(RunKindText, workflowID, CreateOutputDir(), len(chunks))
+	run.start(executor)
+	runConcurrent(opts.Concurrency, len(chunks), func(i int) {
+		prompt := ApplyTemplate(opts.Template, chunks[i])
+		run.Tasks[i] = runTask(taskJob{
+			tag:       "[批量文本]",
+			label:     fmt.Sprintf("第 %d 段", i+1),
+			outputDir: run.OutputDir,
+			baseName:  fmt.Sprintf("text_%d", i+1),
+			create: func() (*TaskCreateResponse, error) {
+				logDebug("[批量文本] 提示词", "index", i+1, "text", prompt)
+				return executor.ExecuteWorkflowWithText(workflowID, prompt)
+			},
+		}, executor)
+	})
+	run.finish(executor)
+	logInfo("批量文本处理完成")
+	return run, nil
+}
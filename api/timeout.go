@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TaskTimeout 任务的最长排队时间和最长执行时间，超过时取消任务
+// 计时从开始等待任务时算起，恢复的任务重新计时
+type TaskTimeout struct {
+	MaxQueueTime time.Duration `json:"maxQueueTime,omitempty"` // 最长排队时间（RUNNING 之前），0 表示不限制
+	MaxRunTime   time.Duration `json:"maxRunTime,omitempty"`   // 最长执行时间（进入 RUNNING 之后），0 表示不限制
+}
+
+// IsZero 判断是否不限制时间
+func (t *TaskTimeout) IsZero() bool {
+	return t == nil || (t.MaxQueueTime <= 0 && t.MaxRunTime <= 0)
+}
+
+// TaskStatusTimeout 任务排队或执行超时，已请求取消
+const TaskStatusTimeout = "TIMEOUT"
+
+// ErrTaskTimeout 任务排队或执行超时
+var ErrTaskTimeout = errors.New("任务超时")
+
+// SetTaskTimeout 设置本次运行的超时时间，覆盖各工作流的配置，nil 表示使用工作流配置
+func (we *WorkflowExecutor) SetTaskTimeout(timeout *TaskTimeout) {
+	we.timeout = timeout
+}
+
+// TaskTimeout 返回工作流生效的超时时间，可能为 nil
+func (we *WorkflowExecutor) TaskTimeout(workflowID string) *TaskTimeout {
+	if we.timeout != nil {
+		return we.timeout
+	}
+	if config, exists := we.manager.GetWorkflow(workflowID); exists {
+		return config.Timeout
+	}
+	return nil
+}
+
+// taskClock 记录任务排队和开始执行的时间，判断是否超时
+type taskClock struct {
+	timeout *TaskTimeout
+	queued  time.Time // 开始等待的时间
+	running time.Time // 第一次看到 RUNNING 的时间，之前为零值
+}
+
+// newTaskClock 创建从现在开始计时的时钟
+func newTaskClock(timeout *TaskTimeout) *taskClock {
+	return &taskClock{timeout: timeout, queued: time.Now()}
+}
+
+// check 根据最新状态判断是否超时，超时时返回说明
+func (c *taskClock) check(status string) (string, bool) {
+	if c.timeout.IsZero() {
+		return "", false
+	}
+	now := time.Now()
+	if status == "RUNNING" {
+		if c.running.IsZero() {
+			c.running = now
+		}
+		if c.timeout.MaxRunTime > 0 && now.Sub(c.running) > c.timeout.MaxRunTime {
+			return fmt.Sprintf("执行超过 %s", c.timeout.MaxRunTime), true
+		}
+		return "", false
+	}
+	if c.running.IsZero() && c.timeout.MaxQueueTime > 0 && now.Sub(c.queued) > c.timeout.MaxQueueTime {
+		return fmt.Sprintf("排队超过 %s", c.timeout.MaxQueueTime), true
+	}
+	return "", false
+}
+
+// cancelTimedOut 取消超时的任务，返回包装了 ErrTaskTimeout 的错误
+func cancelTimedOut(taskID, reason string) error {
+	logWarn("任务超时，取消任务", "taskId", taskID, "reason", reason)
+	resp, err := CancelTask(taskID)
+	if err == nil && resp.Code != 0 {
+		err = fmt.Errorf("code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	if err != nil {
+		logError("取消超时任务失败", "taskId", taskID, "error", err)
+		return fmt.Errorf("%w: %s，%s（取消失败: %v）", ErrTaskTimeout, taskID, reason, err)
+	}
+	return fmt.Errorf("%w: %s，%s，已取消", ErrTaskTimeout, taskID, reason)
+}
@@ -24,12 +24,17 @@ type UploadResponse struct {
 // filePath: 本地文件路径
 // fileType: 文件类型，可以是 "image" 或 "video"
 func UploadImage(filePath string, fileType string) (*UploadResponse, error) {
+	return uploadFile(ApiKey, filePath, fileType)
+}
+
+// uploadFile 使用指定的 API Key 上传文件，文件只能被同一账户创建的任务使用
+func uploadFile(apiKey, filePath, fileType string) (*UploadResponse, error) {
 	// 创建multipart表单
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	// 添加apiKey字段
-	if err := writer.WriteField("apiKey", ApiKey); err != nil {
+	if err := writer.WriteField("apiKey", apiKey); err != nil {
 		return nil, fmt.Errorf("写入apiKey失败: %v", err)
 	}
 
@@ -91,11 +96,10 @@ func UploadImage(filePath string, fileType string) (*UploadResponse, error) {
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
 
-	// 添加成功日志
 	if uploadResp.Code == 0 {
-		fmt.Printf("[上传成功] 文件: %s\n", filepath.Base(filePath))
-		fmt.Printf("[上传成功] 类型: %s\n", fileType)
-		fmt.Printf("[上传成功] 服务器文件名: %s\n", uploadResp.Data.FileName)
+		logDebug("[上传成功]", "file", filepath.Base(filePath), "fileType", fileType, "fileName", uploadResp.Data.FileName)
+	} else {
+		logWarn("[上传失败]", "file", filepath.Base(filePath), "code", uploadResp.Code, "msg", uploadResp.Msg)
 	}
 
 	return &uploadResp, nil
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// dirNotifier 目录变化通知，用于在轮询间隔之前尽早发现新文件
+type dirNotifier interface {
+	Events() <-chan struct{} // 目录可能有变化时收到通知，关闭后通道关闭
+	Add(dir string) error    // 添加监控目录
+	Close() error
+}
+
+// WatchOptions 监控模式选项
+type WatchOptions struct {
+	Concurrency int           // 并发数量
+	Inputs      InputOptions  // 输入文件发现选项，Limit 不生效
+	Disposition Disposition   // 任务结束后输入文件的处理策略
+	Interval    time.Duration // 轮询间隔
+	Settle      time.Duration // 文件大小和修改时间保持不变多久后视为写入完成
+}
+
+// DefaultWatchOptions 返回默认监控选项
+func DefaultWatchOptions() WatchOptions {
+	return WatchOptions{
+		Concurrency: 1,
+		Inputs:      DefaultInputOptions(),
+		Disposition: DefaultDisposition(),
+		Interval:    2 * time.Second,
+		Settle:      3 * time.Second,
+	}
+}
+
+// watchedFile 正在等待写入完成的文件
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // 大小和修改时间最后一次变化的时间
+}
+
+// Watch 监控输入目录，新文件写入完成后提交到工作流，任务结束后按策略处理输入文件
+// 在 Linux 上使用 inotify 尽早发现变化，其他平台只定时轮询
+// stop 关闭或执行器被中断后停止监控，等待执行中的任务结束（或按中断策略处理）并返回运行记录
+func Watch(workflowID string, opts WatchOptions, executor *WorkflowExecutor, stop <-chan struct{}) (*RunRecord, error) {
+	config, exists := executor.manager.GetWorkflow(workflowID)
+	if !exists {
+		return nil, fmt.Errorf("工作流不存在: %s", workflowID)
+	}
+	inputOpts := opts.Inputs
+	inputOpts.Limit = 0
+	if len(inputOpts.Kinds) == 0 {
+		inputOpts.Kinds = config.InputKinds()
+	}
+	if opts.Disposition.Mode == DispositionMark {
+		inputOpts.SkipDone = true
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchOptions().Interval
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	// 首次扫描确认目录可读
+	if _, err := DiscoverInputs(inputOpts); err != nil {
+		return nil, err
+	}
+
+	var events <-chan struct{}
+	notifier, err := newDirNotifier()
+	if err != nil {
+		logDebug("[监控] 使用轮询模式", "reason", err)
+	} else {
+		defer notifier.Close()
+		if err := notifier.Add(inputOpts.Dir); err != nil {
+			logWarn("[监控] 无法监控目录变化，使用轮询模式", "error", err)
+		}
+		events = notifier.Events()
+	}
+
+	run := newRunRecord(RunKindWatch, workflowID, CreateOutputDir(), 0)
+	run.start(executor)
+	logInfo("[监控] 开始监控输入目录", "dir", inputOpts.Dir, "kinds", inputOpts.Kinds, "interval", opts.Interval, "settle", opts.Settle)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, opts.Concurrency)
+		pending  = make(map[string]*watchedFile)
+		inFlight = make(map[string]bool)
+		handled  = make(map[string]time.Time) // 已处理且仍留在原位置的文件 -> 处理时的修改时间
+	)
+	submit := func(input InputFile) {
+		defer wg.Done()
+		defer func() { <-sem }()
+		result := processInput(workflowID, input, run.OutputDir, executor)
+		disposeInput("[监控]", opts.Disposition, input, result)
+		run.add(result)
+		if err := run.Save(); err != nil {
+			logError("[监控] 保存运行记录失败", "error", err)
+		}
+		mu.Lock()
+		delete(inFlight, input.Path)
+		handled[input.Path] = input.ModTime
+		mu.Unlock()
+	}
+
+	scan := func() {
+		files, err := DiscoverInputs(inputOpts)
+		if err != nil {
+			logWarn("[监控] 扫描输入目录失败", "dir", inputOpts.Dir, "error", err)
+			return
+		}
+		now := time.Now()
+		present := make(map[string]bool, len(files))
+		for _, file := range files {
+			present[file.Path] = true
+			if notifier != nil && inputOpts.Recursive {
+				notifier.Add(filepath.Dir(file.Path))
+			}
+
+			mu.Lock()
+			busy := inFlight[file.Path]
+			modTime, done := handled[file.Path]
+			mu.Unlock()
+			if busy || (done && modTime.Equal(file.ModTime)) {
+				continue
+			}
+
+			w, ok := pending[file.Path]
+			if !ok || w.size != file.Size || !w.modTime.Equal(file.ModTime) {
+				if !ok {
+					logDebug("[监控] 发现新文件，等待写入完成", "path", file.Path)
+				}
+				pending[file.Path] = &watchedFile{size: file.Size, modTime: file.ModTime, since: now}
+				continue
+			}
+			if now.Sub(w.since) < opts.Settle {
+				continue
+			}
+
+			// 文件已稳定，提交任务；并发已满时留到下次扫描
+			select {
+			case sem <- struct{}{}:
+			default:
+				continue
+			}
+			delete(pending, file.Path)
+			mu.Lock()
+			inFlight[file.Path] = true
+			mu.Unlock()
+			logInfo("[监控] 提交新文件", "path", file.Path, "size", file.Size)
+			wg.Add(1)
+			go submit(file)
+		}
+		// 清理已被删除或移走的文件
+		for p := range pending {
+			if !present[p] {
+				delete(pending, p)
+			}
+		}
+		mu.Lock()
+		for p := range handled {
+			if !present[p] {
+				delete(handled, p)
+			}
+		}
+		mu.Unlock()
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	scan()
+	for {
+		select {
+		case <-stop:
+			logInfo("[监控] 停止监控，等待执行中的任务结束")
+			wg.Wait()
+			run.finish(executor)
+			return run, nil
+		case <-executor.interrupted():
+			logInfo("[监控] 运行被中断，停止监控")
+			wg.Wait()
+			run.finish(executor)
+			return run, nil
+		case <-ticker.C:
+			scan()
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			scan()
+		}
+	}
+}
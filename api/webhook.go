@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 通知事件
+const (
+	EventTaskSucceeded = "task.succeeded" // 任务成功
+	EventTaskFailed    = "task.failed"    // 任务失败（服务器返回 FAILED 或本地出错）
+	EventRunFinished   = "run.finished"   // 一次批量运行结束
+)
+
+// WebhookSignatureHeader 请求签名头，值为 sha256=<请求体的 HMAC-SHA256 十六进制>
+const WebhookSignatureHeader = "X-RunningHub-Signature"
+
+// webhookRetryDelay 第一次重试前的等待时间，之后每次加倍
+var webhookRetryDelay = time.Second
+
+// Notifier 任务和批量运行结束时的通知，由执行器在任务结束后同步调用
+type Notifier interface {
+	TaskFinished(result *TaskResult) // 任务结束，可以修改任务结果中的通知相关字段
+	RunFinished(run *RunRecord)      // 批量运行结束，运行记录已保存
+}
+
+// taskEvent 返回任务结果对应的事件
+func taskEvent(result *TaskResult) string {
+	if result.Succeeded() {
+		return EventTaskSucceeded
+	}
+	return EventTaskFailed
+}
+
+// Webhook 向指定 URL 发送 JSON 通知
+type Webhook struct {
+	URL     string        // 接收通知的地址
+	Secret  string        // 签名密钥，为空时不签名
+	Events  []string      // 订阅的事件，为空时订阅全部事件
+	Retries int           // 发送失败（网络错误或非 2xx 响应）时的重试次数
+	Timeout time.Duration // 单次请求超时，为 0 时为 10 秒
+}
+
+// WebhookTask 通知中的任务信息
+type WebhookTask struct {
+	Label      string       `json:"label"`
+	TaskID     string       `json:"taskId,omitempty"`
+	WorkflowID string       `json:"workflowId,omitempty"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Inputs     []string     `json:"inputs,omitempty"`  // 本地输入文件
+	Outputs    []TaskOutput `json:"outputs,omitempty"` // 服务器返回的结果
+	Files      []string     `json:"files,omitempty"`   // 已保存的本地文件
+	Started    time.Time    `json:"started"`
+	Duration   float64      `json:"duration"` // 耗时（秒）
+	Coins      Coins        `json:"coins"`
+}
+
+// WebhookRun 通知中的批量运行信息
+type WebhookRun struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	WorkflowID string    `json:"workflowId,omitempty"`
+	OutputDir  string    `json:"outputDir"`
+	Record     string    `json:"record"` // 运行记录文件路径
+	Started    time.Time `json:"started"`
+	Duration   float64   `json:"duration"` // 总耗时（秒）
+	Total      int       `json:"total"`
+	Succeeded  int       `json:"succeeded"`
+	Failed     int       `json:"failed"`
+	TimedOut   int       `json:"timedOut"` // 超时取消的任务数，已计入 failed
+	Canceled   int       `json:"canceled"`
+	Skipped    int       `json:"skipped"`
+	Detached   int       `json:"detached"`
+	Files      int       `json:"files"`
+	Coins      Coins     `json:"coins"`
+}
+
+// WebhookPayload 通知请求体
+type WebhookPayload struct {
+	Event string       `json:"event"`
+	Time  time.Time    `json:"time"`
+	Task  *WebhookTask `json:"task,omitempty"` // task.* 事件
+	Run   *WebhookRun  `json:"run,omitempty"`  // run.finished 事件
+}
+
+// ParseWebhookEvents 解析逗号分隔的事件列表
+func ParseWebhookEvents(s string) ([]string, error) {
+	events := splitList(s)
+	for _, event := range events {
+		switch event {
+		case EventTaskSucceeded, EventTaskFailed, EventRunFinished:
+		default:
+			return nil, fmt.Errorf("无效的事件: %s（支持 %s、%s、%s）", event, EventTaskSucceeded, EventTaskFailed, EventRunFinished)
+		}
+	}
+	return events, nil
+}
+
+// subscribed 判断是否订阅了事件
+func (w *Webhook) subscribed(event string) bool {
+	return len(w.Events) == 0 || containsString(w.Events, event)
+}
+
+// NewTaskPayload 根据任务结果生成 task.* 事件，命令行的 -json 输出也使用该格式
+func NewTaskPayload(result *TaskResult) WebhookPayload {
+	return WebhookPayload{
+		Event: taskEvent(result),
+		Time:  time.Now(),
+		Task: &WebhookTask{
+			Label:      result.Label,
+			TaskID:     result.TaskID,
+			WorkflowID: result.WorkflowID,
+			Status:     result.Status,
+			Error:      result.Error,
+			Inputs:     result.Inputs,
+			Outputs:    result.Outputs,
+			Files:      result.Files,
+			Started:    result.Started,
+			Duration:   result.Duration.Seconds(),
+			Coins:      result.Coins,
+		},
+	}
+}
+
+// NewRunPayload 根据运行记录生成 run.finished 事件
+func NewRunPayload(run *RunRecord) WebhookPayload {
+	stats := run.Stats()
+	return WebhookPayload{
+		Event: EventRunFinished,
+		Time:  time.Now(),
+		Run: &WebhookRun{
+			ID:         run.ID,
+			Kind:       run.Kind,
+			WorkflowID: run.WorkflowID,
+			OutputDir:  run.OutputDir,
+			Record:     run.Path(),
+			Started:    run.Started,
+			Duration:   run.Duration().Seconds(),
+			Total:      stats.Total,
+			Succeeded:  stats.Succeeded,
+			Failed:     stats.Failed,
+			TimedOut:   stats.TimedOut,
+			Canceled:   stats.Canceled,
+			Skipped:    stats.Skipped,
+			Detached:   stats.Detached,
+			Files:      stats.Files,
+			Coins:      stats.Coins,
+		},
+	}
+}
+
+// TaskFinished 发送任务事件
+func (w *Webhook) TaskFinished(result *TaskResult) {
+	if !w.subscribed(taskEvent(result)) {
+		return
+	}
+	w.send(NewTaskPayload(result))
+}
+
+// RunFinished 发送批量运行结束事件
+func (w *Webhook) RunFinished(run *RunRecord) {
+	if !w.subscribed(EventRunFinished) {
+		return
+	}
+	w.send(NewRunPayload(run))
+}
+
+// send 发送通知，失败时按 webhookRetryDelay 开始加倍的间隔（1s、2s、4s...）重试，最终失败只记录日志
+func (w *Webhook) send(payload WebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logError("[通知] 序列化通知失败", "error", err)
+		return
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	delay := webhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		err = w.post(client, body)
+		if err == nil {
+			logDebug("[通知] 发送成功", "url", w.URL, "event", payload.Event)
+			return
+		}
+		if attempt >= w.Retries {
+			break
+		}
+		logWarn("[通知] 发送失败，稍后重试", "url", w.URL, "event", payload.Event, "attempt", attempt+1, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	logError("[通知] 发送失败", "url", w.URL, "event", payload.Event, "error", err)
+}
+
+// post 发送一次请求
+func (w *Webhook) post(client *http.Client, body []byte) error {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "runninghub-webhook")
+	if w.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("响应状态 %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// SignWebhook 计算请求体签名，接收方可以用相同的密钥校验 X-RunningHub-Signature
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver 记录收到的通知，前 failFirst 次请求返回 500
+type webhookReceiver struct {
+	mu        sync.Mutex
+	failFirst int
+	requests  int
+	bodies    [][]byte
+	headers   []http.Header
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests++
+	if rcv.requests <= rcv.failFirst {
+		http.Error(w, "temporarily unavailable", http.StatusInternalServerError)
+		return
+	}
+	rcv.bodies = append(rcv.bodies, body)
+	rcv.headers = append(rcv.headers, r.Header.Clone())
+}
+
+// payloads 返回已成功接收的通知
+func (rcv *webhookReceiver) payloads(t *testing.T) []WebhookPayload {
+	t.Helper()
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	var payloads []WebhookPayload
+	for _, body := range rcv.bodies {
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("通知不是合法的 JSON: %v\n%s", err, body)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+// newWebhookServer 启动接收通知的测试服务
+func newWebhookServer(t *testing.T, failFirst int) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+	rcv := &webhookReceiver{failFirst: failFirst}
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+	return rcv, server
+}
+
+// fastWebhookRetry 缩短重试间隔
+func fastWebhookRetry(t *testing.T) {
+	t.Helper()
+	old := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = old })
+}
+
+func TestWebhookTaskPayload(t *testing.T) {
+	rcv, server := newWebhookServer(t, 0)
+	hook := &Webhook{URL: server.URL}
+	started := time.Date(2025, 6, 10, 15, 30, 0, 0, time.UTC)
+	hook.TaskFinished(&TaskResult{
+		Label:      "inputs/cat.png",
+		WorkflowID: "1930266544381792258",
+		TaskID:     "1931000000000000001",
+		Status:     "SUCCESS",
+		Inputs:     []string{"inputs/cat.png"},
+		Outputs:    []TaskOutput{{FileUrl: "https://example.com/out.png", FileType: "png", NodeId: "9"}},
+		Files:      []string{"outputs/cat_0.png"},
+		Started:    started,
+		Duration:   90 * time.Second,
+	})
+
+	payloads := rcv.payloads(t)
+	if len(payloads) != 1 {
+		t.Fatalf("收到 %d 个通知，期望 1 个", len(payloads))
+	}
+	payload := payloads[0]
+	if payload.Event != EventTaskSucceeded || payload.Task == nil || payload.Run != nil {
+		t.Fatalf("事件错误: %+v", payload)
+	}
+	task := payload.Task
+	if task.TaskID != "1931000000000000001" || task.WorkflowID != "1930266544381792258" || task.Status != "SUCCESS" || task.Label != "inputs/cat.png" {
+		t.Errorf("任务信息错误: %+v", task)
+	}
+	if len(task.Outputs) != 1 || task.Outputs[0].NodeId != "9" || len(task.Files) != 1 || task.Files[0] != "outputs/cat_0.png" {
+		t.Errorf("输出错误: %+v", task)
+	}
+	if !task.Started.Equal(started) || task.Duration != 90 {
+		t.Errorf("时间错误: started=%v duration=%v", task.Started, task.Duration)
+	}
+	if got := rcv.headers[0].Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rcv.headers[0].Get(WebhookSignatureHeader); got != "" {
+		t.Errorf("未设置密钥时不应签名，得到 %q", got)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	rcv, server := newWebhookServer(t, 0)
+	hook := &Webhook{URL: server.URL, Secret: "s3cret"}
+	hook.TaskFinished(&TaskResult{Label: "a", TaskID: "1", Status: "FAILED", Error: "任务执行失败"})
+
+	if len(rcv.bodies) != 1 {
+		t.Fatalf("收到 %d 个通知，期望 1 个", len(rcv.bodies))
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(rcv.bodies[0])
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := rcv.headers[0].Get(WebhookSignatureHeader); got != want {
+		t.Errorf("签名 = %q，期望 %q", got, want)
+	}
+	if payload := rcv.payloads(t)[0]; payload.Event != EventTaskFailed || payload.Task.Error != "任务执行失败" {
+		t.Errorf("事件错误: %+v", payload)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	fastWebhookRetry(t)
+	tests := []struct {
+		name      string
+		failFirst int
+		retries   int
+		requests  int
+		delivered int
+	}{
+		{"第一次成功", 0, 3, 1, 1},
+		{"重试后成功", 2, 3, 3, 1},
+		{"用尽重试次数", 5, 2, 3, 0},
+		{"不重试", 1, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv, server := newWebhookServer(t, tt.failFirst)
+			hook := &Webhook{URL: server.URL, Retries: tt.retries}
+			hook.TaskFinished(&TaskResult{Label: "a", TaskID: "1", Status: "SUCCESS"})
+			if rcv.requests != tt.requests || len(rcv.bodies) != tt.delivered {
+				t.Errorf("请求 %d 次、送达 %d 个，期望请求 %d 次、送达 %d 个", rcv.requests, len(rcv.bodies), tt.requests, tt.delivered)
+			}
+		})
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	succeeded := &TaskResult{Label: "a", TaskID: "1", Status: "SUCCESS"}
+	failed := &TaskResult{Label: "b", TaskID: "2", Status: "FAILED"}
+	timedOut := &TaskResult{Label: "c", TaskID: "3", Status: TaskStatusTimeout}
+	run := &RunRecord{ID: "20250610_153000_images", Kind: RunKindImages, Tasks: []*TaskResult{succeeded, failed, timedOut}}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   []string
+	}{
+		{"默认订阅全部", nil, []string{EventTaskSucceeded, EventTaskFailed, EventTaskFailed, EventRunFinished}},
+		{"只订阅失败", []string{EventTaskFailed}, []string{EventTaskFailed, EventTaskFailed}},
+		{"只订阅运行结束", []string{EventRunFinished}, []string{EventRunFinished}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv, server := newWebhookServer(t, 0)
+			hook := &Webhook{URL: server.URL, Events: tt.events}
+			for _, result := range run.Tasks {
+				hook.TaskFinished(result)
+			}
+			hook.RunFinished(run)
+
+			var got []string
+			for _, payload := range rcv.payloads(t) {
+				got = append(got, payload.Event)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("事件 = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWebhookEvents(t *testing.T) {
+	events, err := ParseWebhookEvents("task.failed, run.finished")
+	if err != nil || strings.Join(events, ",") != "task.failed,run.finished" {
+		t.Errorf("ParseWebhookEvents = %v, %v", events, err)
+	}
+	if _, err := ParseWebhookEvents("task.done"); err == nil {
+		t.Error("无效的事件应返回错误")
+	}
+}
+
+// fakeRunningHub 模拟 RunningHub 接口的 RoundTripper，其他地址（如测试用的通知服务）交给 next
+type fakeRunningHub struct {
+	next   http.RoundTripper
+	status string // 任务的最终状态
+}
+
+func (f *fakeRunningHub) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host != "www.runninghub.cn" {
+		return f.next.RoundTrip(r)
+	}
+	body := `{"code":0,"data":[{"fileUrl":"https://www.runninghub.cn/files/out.png","fileType":"png","nodeId":"9"}]}`
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/create"):
+		body = `{"code":0,"data":{"taskId":"1931000000000000001","taskStatus":"QUEUED"}}`
+	case strings.HasSuffix(r.URL.Path, "/status"):
+		body = `{"code":0,"data":"` + f.status + `"}`
+	case strings.HasPrefix(r.URL.Path, "/files/"):
+		body = "png"
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: r}, nil
+}
+
+// TestRunTaskNotifiesWebhook 单个任务（run、rerun 使用的 RunTask）结束时同样发送通知
+func TestRunTaskNotifiesWebhook(t *testing.T) {
+	for _, status := range []string{"SUCCESS", "FAILED"} {
+		t.Run(status, func(t *testing.T) {
+			old := http.DefaultTransport
+			http.DefaultTransport = &fakeRunningHub{next: old, status: status}
+			t.Cleanup(func() { http.DefaultTransport = old })
+
+			rcv, server := newWebhookServer(t, 0)
+			manager := NewWorkflowManager()
+			manager.RegisterWorkflow(&WorkflowConfig{ID: "w", Params: []NodeParam{{NodeId: "6", FieldName: "text", FieldValue: "a cat"}}})
+			executor := NewWorkflowExecutor(manager)
+			executor.AddNotifier(&Webhook{URL: server.URL, Secret: "s3cret"})
+
+			result := executor.RunTask(TaskOptions{
+				OutputDir: t.TempDir(),
+				Create: func() (*TaskCreateResponse, error) {
+					return executor.ExecuteWorkflow("w")
+				},
+			})
+			if result.Status != status {
+				t.Fatalf("任务状态 = %s，期望 %s（%s）", result.Status, status, result.Error)
+			}
+			payloads := rcv.payloads(t)
+			if len(payloads) != 1 {
+				t.Fatalf("收到 %d 个通知，期望 1 个", len(payloads))
+			}
+			want := EventTaskSucceeded
+			if status != "SUCCESS" {
+				want = EventTaskFailed
+			}
+			if payloads[0].Event != want || payloads[0].Task.TaskID != "1931000000000000001" || payloads[0].Task.Status != status {
+				t.Errorf("通知错误: %+v", payloads[0].Task)
+			}
+			if status == "SUCCESS" {
+				if len(payloads[0].Task.Files) != 1 {
+					t.Fatalf("通知中的本地文件 = %v", payloads[0].Task.Files)
+				}
+				if _, err := os.Stat(payloads[0].Task.Files[0]); err != nil {
+					t.Errorf("结果文件未保存: %v", err)
+				}
+			}
+			if rcv.headers[0].Get(WebhookSignatureHeader) == "" {
+				t.Error("缺少签名")
+			}
+		})
+	}
+}
@@ -2,19 +2,68 @@ package api
 
 // NodeParam 节点参数配置
 type NodeParam struct {
-	NodeId     string      `json:"nodeId"`     // 节点ID
-	FieldName  string      `json:"fieldName"`  // 字段名
-	FieldValue interface{} `json:"fieldValue"` // 字段值
-	IsImage    bool        `json:"isImage"`    // 是否为图片输入节点
+	NodeId     string      `json:"nodeId"`           // 节点ID
+	FieldName  string      `json:"fieldName"`        // 字段名
+	FieldValue interface{} `json:"fieldValue"`       // 字段值
+	IsImage    bool        `json:"isImage"`          // 是否为图片输入节点
+	IsSeed     bool        `json:"isSeed,omitempty"` // 是否为随机种子字段，字段名为 seed/noise_seed 时可省略
+	Kind       InputKind   `json:"kind,omitempty"`   // 输入文件类型，为空时根据字段名推断
+}
+
+// InputKind 返回输入节点接受的文件类型，非文件输入节点返回空字符串
+func (p NodeParam) InputKind() InputKind {
+	if p.Kind != "" {
+		return p.Kind
+	}
+	switch p.FieldName {
+	case "image":
+		return InputImage
+	case "video":
+		return InputVideo
+	case "audio":
+		return InputAudio
+	}
+	if p.IsImage {
+		return InputImage
+	}
+	return ""
 }
 
 // WorkflowConfig 工作流配置
 type WorkflowConfig struct {
-	ID          string            `json:"id"`          // 工作流ID
-	Name        string            `json:"name"`        // 工作流名称
-	Description string            `json:"description"` // 工作流描述
-	NodeConfigs map[string]string `json:"nodeConfigs"` // 节点配置，key为节点ID，value为节点描述
-	Params      []NodeParam       `json:"params"`      // 固定参数配置
+	ID          string            `json:"id"`                // 工作流ID
+	Name        string            `json:"name"`              // 工作流名称
+	Description string            `json:"description"`       // 工作流描述
+	NodeConfigs map[string]string `json:"nodeConfigs"`       // 节点配置，key为节点ID，value为节点描述
+	Params      []NodeParam       `json:"params"`            // 固定参数配置
+	Outputs     *OutputSelector   `json:"outputs,omitempty"` // 输出筛选规则，为空时下载全部输出
+	Retry       *RetryPolicy      `json:"retry,omitempty"`   // 任务执行失败时的重试规则，为空时不重试
+	Timeout     *TaskTimeout      `json:"timeout,omitempty"` // 任务的最长排队和执行时间，为空时不限制
+}
+
+// InputKinds 返回工作流接受的输入文件类型（去重，按节点顺序）
+func (c *WorkflowConfig) InputKinds() []InputKind {
+	var kinds []InputKind
+	seen := make(map[InputKind]bool)
+	for _, param := range c.Params {
+		kind := param.InputKind()
+		if kind == "" || seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// AcceptsInput 返回工作流是否有 kind 类型的文件输入节点
+func (c *WorkflowConfig) AcceptsInput(kind InputKind) bool {
+	for _, k := range c.InputKinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
 }
 
 // WorkflowManager 工作流管理器
@@ -148,7 +197,7 @@ var (
 			},
 		},
 	}
-	
+
 	// ATI字节最新轨迹驱动wan视频生成版
 	ATiWorkflow = &WorkflowConfig{
 		ID:          "1931384612306792449",
@@ -254,12 +303,14 @@ var (
 				FieldName:  "file",
 				FieldValue: "", // 视频路径会在执行时设置
 				IsImage:    true,
+				Kind:       InputVideo,
 			},
 			{
 				NodeId:     "1",
-				FieldName:  "audio",  // 保持为 file
-				FieldValue: "", // 音频路径会在执行时设置
+				FieldName:  "audio", // 保持为 file
+				FieldValue: "",      // 音频路径会在执行时设置
 				IsImage:    true,
+				Kind:       InputAudio,
 			},
 		},
 	}
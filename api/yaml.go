@@ -0,0 +1,176 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 配置文件只用到 YAML 的一个小子集，为了不引入依赖在这里实现:
+//   - 以缩进表示层级的 key: value 映射
+//   - "- 值" 形式的列表和 [a, b] 形式的单行列表
+//   - 单引号或双引号字符串，以及 # 注释
+// 不支持多行字符串、锚点、多文档等其他语法。
+
+// yamlLine 去掉注释后的一个非空行
+type yamlLine struct {
+	num    int    // 行号
+	indent int    // 缩进空格数
+	text   string // 去掉缩进和注释后的内容
+}
+
+// parseYAML 解析 YAML 子集，返回顶层映射，值为 string、[]string 或 map[string]interface{}
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if i == 0 {
+			raw = strings.TrimPrefix(raw, "\ufeff")
+		}
+		if strings.Contains(raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))], "\t") {
+			return nil, fmt.Errorf("第 %d 行: 缩进不能使用 Tab", i+1)
+		}
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		if strings.TrimSpace(text) == "" || text == "---" {
+			continue
+		}
+		trimmed := strings.TrimLeft(text, " ")
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("第 %d 行: 缩进不正确", lines[next].num)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("顶层必须是 key: value 映射")
+	}
+	return m, nil
+}
+
+// parseYAMLBlock 解析从 lines[i] 开始、缩进为 indent 的映射或列表，返回值和下一个未处理的行
+func parseYAMLBlock(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if strings.HasPrefix(lines[i].text, "- ") || lines[i].text == "-" {
+		var list []string
+		for i < len(lines) && lines[i].indent == indent && strings.HasPrefix(lines[i].text, "-") {
+			item := strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-"))
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, i, fmt.Errorf("第 %d 行: %v", lines[i].num, err)
+			}
+			list = append(list, value)
+			i++
+		}
+		return list, i, nil
+	}
+
+	m := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, rest, ok := strings.Cut(line.text, ":")
+		if !ok || (rest != "" && rest[0] != ' ') {
+			return nil, i, fmt.Errorf("第 %d 行: 应为 key: value", line.num)
+		}
+		key, rest = strings.TrimSpace(key), strings.TrimSpace(rest)
+		if unquoted, err := parseYAMLScalar(key); err == nil {
+			key = unquoted
+		}
+		if _, exists := m[key]; exists {
+			return nil, i, fmt.Errorf("第 %d 行: 重复的 key: %s", line.num, key)
+		}
+		i++
+
+		switch {
+		case rest != "" && strings.HasPrefix(rest, "["):
+			list, err := parseYAMLFlowList(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("第 %d 行: %v", line.num, err)
+			}
+			m[key] = list
+		case rest != "":
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("第 %d 行: %v", line.num, err)
+			}
+			m[key] = value
+		case i < len(lines) && (lines[i].indent > indent || (lines[i].indent == indent && strings.HasPrefix(lines[i].text, "-"))):
+			child, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			m[key], i = child, next
+		default:
+			m[key] = ""
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("第 %d 行: 缩进不正确", lines[i].num)
+	}
+	return m, i, nil
+}
+
+// parseYAMLScalar 解析标量，去掉引号
+func parseYAMLScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("无效的字符串: %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("无效的字符串: %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s == "~" || s == "null":
+		return "", nil
+	}
+	return s, nil
+}
+
+// parseYAMLFlowList 解析 [a, "b", c] 形式的单行列表
+func parseYAMLFlowList(s string) ([]string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("无效的列表: %s", s)
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return []string{}, nil
+	}
+	var list []string
+	for _, item := range strings.Split(inner, ",") {
+		value, err := parseYAMLScalar(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// stripYAMLComment 去掉引号字符串之外、位于行首或空白之后的 # 注释
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t:[,-", line[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
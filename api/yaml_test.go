@@ -0,0 +1,119 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]interface{}
+	}{
+		{
+			name: "简单映射",
+			data: "profile: work\napi_key: abc123\n",
+			want: map[string]interface{}{"profile": "work", "api_key": "abc123"},
+		},
+		{
+			name: "双引号",
+			data: `api_key: "a b\"c\\d"` + "\n" + `"quoted key": "x: y"`,
+			want: map[string]interface{}{"api_key": `a b"c\d`, "quoted key": "x: y"},
+		},
+		{
+			name: "单引号",
+			data: "api_key: 'it''s'\nempty: ''",
+			want: map[string]interface{}{"api_key": "it's", "empty": ""},
+		},
+		{
+			name: "注释",
+			data: "# 整行注释\napi_key: abc # 行尾注释\n  # 缩进的注释\nprofile: work#不是注释\n",
+			want: map[string]interface{}{"api_key": "abc", "profile": "work#不是注释"},
+		},
+		{
+			name: "引号中的井号",
+			data: `api_key: "abc # def"` + "\nother: 'x #y' # 注释",
+			want: map[string]interface{}{"api_key": "abc # def", "other": "x #y"},
+		},
+		{
+			name: "嵌套 profile",
+			data: "profiles:\n  work:\n    api_key: w\n    max_tasks: 3\n  personal:\n    api_key: p\nprofile: work\n",
+			want: map[string]interface{}{
+				"profiles": map[string]interface{}{
+					"work":     map[string]interface{}{"api_key": "w", "max_tasks": "3"},
+					"personal": map[string]interface{}{"api_key": "p"},
+				},
+				"profile": "work",
+			},
+		},
+		{
+			name: "列表",
+			data: "pool: [work, \"personal\"]\nempty: []\nitems:\n  - a\n  - 'b'\nsame:\n- c\n",
+			want: map[string]interface{}{
+				"pool":  []string{"work", "personal"},
+				"empty": []string{},
+				"items": []string{"a", "b"},
+				"same":  []string{"c"},
+			},
+		},
+		{
+			name: "空值和 null",
+			data: "a:\nb: ~\nc: null\n",
+			want: map[string]interface{}{"a": "", "b": "", "c": ""},
+		},
+		{
+			name: "BOM、CRLF 和文档分隔符",
+			data: "\ufeff---\r\napi_key: abc\r\n",
+			want: map[string]interface{}{"api_key": "abc"},
+		},
+		{
+			name: "空文件",
+			data: "# 只有注释\n\n",
+			want: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseYAML 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML = %#v，期望 %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // 错误信息中应包含的内容
+	}{
+		{"Tab 缩进", "profiles:\n\twork: x\n", "第 2 行: 缩进不能使用 Tab"},
+		{"值后多余的缩进", "api_key: abc\n  profile: work\n", "第 2 行: 缩进不正确"},
+		{"嵌套映射缩进不一致", "profiles:\n  work:\n    api_key: w\n   personal:\n", "第 4 行: 缩进不正确"},
+		{"首行缩进后回退", "  api_key: abc\nprofile: work\n", "第 2 行: 缩进不正确"},
+		{"缺少冒号", "api_key abc\n", "第 1 行: 应为 key: value"},
+		{"冒号后缺少空格", "api_key:abc\n", "第 1 行: 应为 key: value"},
+		{"重复的 key", "api_key: a\napi_key: b\n", "第 2 行: 重复的 key: api_key"},
+		{"未闭合的双引号", "api_key: \"abc\n", "第 1 行: 无效的字符串"},
+		{"未闭合的单引号", "api_key: 'abc\n", "第 1 行: 无效的字符串"},
+		{"未闭合的列表", "pool: [a, b\n", "第 1 行: 无效的列表"},
+		{"顶层是列表", "- a\n- b\n", "顶层必须是 key: value 映射"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.data))
+			if err == nil {
+				t.Fatalf("期望返回错误，得到 %#v", got)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %q，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
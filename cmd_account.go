@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"runninghub/api"
+)
+
+// accountCommand 查询账户信息
+var accountCommand = &command{
+	name:    "account",
+	summary: "查询账户的剩余金币和当前任务数，使用 Key 池时查询每个账户",
+	run:     runAccount,
+}
+
+// serveCommand 以服务模式运行
+var serveCommand = &command{
+	name:    "serve",
+	summary: "以服务模式运行，提供 HTTP 作业提交接口",
+	run:     runServe,
+}
+
+func runAccount(fs *flag.FlagSet, args []string) int {
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 0 {
+		return usageError(fs, "account 命令不接受位置参数")
+	}
+	if err := setupApiKey(); err != nil {
+		return fail("%v", err)
+	}
+	if !printAccountStatus() {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// accountDocument -json 模式下 account 命令的输出
+type accountDocument struct {
+	Accounts []accountEntry `json:"accounts"`
+}
+
+// accountEntry 一个账户的状态，Profile 在未使用 Key 池时为空
+type accountEntry struct {
+	Profile           string `json:"profile,omitempty"`
+	RemainCoins       string `json:"remainCoins,omitempty"`
+	CurrentTaskCounts string `json:"currentTaskCounts,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// printAccountStatus 获取并打印账户信息，使用 Key 池时打印每个账户，全部获取成功时返回 true
+func printAccountStatus() bool {
+	var entries []accountEntry
+	if pool := api.GetKeyPool(); pool != nil {
+		for _, p := range pool.Profiles() {
+			entries = append(entries, accountStatus(p.Name, p.ApiKey))
+		}
+	} else {
+		entries = append(entries, accountStatus("", api.GetApiKey()))
+	}
+
+	ok := true
+	for _, entry := range entries {
+		if entry.Error != "" {
+			ok = false
+		}
+	}
+	if global.json {
+		printJSON(accountDocument{Accounts: entries})
+		return ok
+	}
+	for _, entry := range entries {
+		tag := "[账户信息]"
+		if entry.Profile != "" {
+			tag = "[账户信息 " + entry.Profile + "]"
+		}
+		if entry.Error != "" {
+			fmt.Printf("%s 获取失败: %s\n", tag, entry.Error)
+		} else {
+			fmt.Printf("%s 剩余金币: %s，当前任务数: %s\n", tag, entry.RemainCoins, entry.CurrentTaskCounts)
+		}
+	}
+	return ok
+}
+
+// accountStatus 获取一个账户的信息
+func accountStatus(profile, apiKey string) accountEntry {
+	entry := accountEntry{Profile: profile}
+	status, err := api.GetAccountStatus(apiKey)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if status.Code != 0 {
+		entry.Error = status.Msg
+		return entry
+	}
+	entry.RemainCoins = status.Data.RemainCoins
+	entry.CurrentTaskCounts = status.Data.CurrentTaskCounts
+	// 尝试转换为 int
+	if coins, err := strconv.Atoi(entry.RemainCoins); err == nil {
+		entry.RemainCoins = fmt.Sprintf("%d", coins)
+	}
+	if tasks, err := strconv.Atoi(entry.CurrentTaskCounts); err == nil {
+		entry.CurrentTaskCounts = fmt.Sprintf("%d", tasks)
+	}
+	return entry
+}
+
+func runServe(fs *flag.FlagSet, args []string) int {
+	addr := fs.String("addr", "127.0.0.1:8080", "监听地址，默认只接受本机连接，如 :8080 监听所有网卡")
+	jobsDir := fs.String("jobs-dir", "jobs", "保存作业记录的目录")
+	inputRoot := fs.String("input-root", "", "允许请求以路径指定的输入文件所在的目录，未指定时输入文件只能通过 multipart 上传")
+	token := fs.String("token", "", "访问令牌，设置后请求需带 Authorization: Bearer <令牌>，未指定时读取环境变量 "+api.EnvServeToken)
+	concurrency := fs.Int("concurrency", 1, "同时执行的作业数量")
+	var execOpts executorOptions
+	execOpts.register(fs, false)
+	// 按 Ctrl+C 时停止接受请求，执行中的作业按 -on-interrupt 处理后退出
+	execOpts.registerInterrupt(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 0 {
+		return usageError(fs, "serve 命令不接受位置参数")
+	}
+
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	store, err := api.NewJobStore(*jobsDir)
+	if err != nil {
+		return fail("打开作业目录失败: %v", err)
+	}
+	server := api.NewServer(executor, store, *concurrency)
+	if err := server.SetInputRoot(*inputRoot); err != nil {
+		return fail("%v", err)
+	}
+	if *token == "" {
+		*token = os.Getenv(api.EnvServeToken)
+	}
+	server.SetToken(*token)
+	if err := server.ListenAndServe(*addr); err != nil {
+		return fail("服务退出: %v", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"time"
+
+	"runninghub/api"
+)
+
+// batchCommand 批量处理命令组
+var batchCommand = &command{
+	name:    "batch",
+	summary: "批量处理: images、text、manifest、sweep、pipeline、resume",
+	children: []*command{
+		{name: "images", args: "[工作流ID]", summary: "批量处理输入目录中的图片/视频/音频，加 -watch 持续监控新文件", run: runBatchImages},
+		{name: "text", args: "[工作流ID]", summary: "把文本文件拆分为多段，每段作为提示词提交一个任务", run: runBatchText},
+		{name: "manifest", args: "<清单文件>", summary: "按 CSV/JSONL 清单批量执行任务，每行指定工作流和输入", run: runBatchManifest},
+		{name: "sweep", args: "<扫描配置>", summary: "按 JSON 扫描配置提交参数组合的笛卡尔积，生成对比索引", run: runBatchSweep},
+		{name: "pipeline", args: "<流水线配置>", summary: "按 JSON 配置执行多阶段流水线，前一阶段的输出作为后续阶段的输入", run: runBatchPipeline},
+		{name: "resume", args: "<run_*.json>", summary: "继续等待被 Ctrl+C 中断的运行中未等待结束的任务，下载结果并更新运行记录", run: runBatchResume},
+	},
+}
+
+// reportCommand 根据运行记录重新生成报告
+var reportCommand = &command{
+	name:    "report",
+	args:    "<run_*.json>",
+	summary: "根据已保存的运行记录重新生成 HTML 报告",
+	run:     runReport,
+}
+
+func runBatchImages(fs *flag.FlagSet, args []string) int {
+	workflowID := fs.String("workflow", "", "要执行的工作流ID，也可以作为位置参数指定")
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	inputDir := fs.String("input-dir", "inputs", "批量处理的输入目录")
+	recursive := fs.Bool("recursive", false, "递归处理输入目录的子目录，输出保持相同的子目录结构")
+	var includes, excludes listFlag
+	fs.Var(&includes, "include", "只处理匹配的文件，glob 模式，可重复指定或用逗号分隔（不含 / 时只匹配文件名）")
+	fs.Var(&excludes, "exclude", "排除匹配的文件，glob 模式，可重复指定或用逗号分隔")
+	kinds := fs.String("kinds", "", "接受的输入类型: image,video,audio（默认根据工作流推断）")
+	sortBy := fs.String("sort", api.SortByName, "输入文件排序方式: name, mtime, size")
+	reverse := fs.Bool("reverse", false, "倒序处理输入文件")
+	limit := fs.Int("limit", 0, "最多处理的文件数，0 表示不限制")
+	disposition := fs.String("disposition", string(api.DispositionMove), "任务结束后输入文件的处理方式: move, copy, mark, delete, none")
+	doneDir := fs.String("done-dir", "tmp", "成功的输入文件移动/复制到的目录")
+	failedDir := fs.String("failed-dir", "", "失败的输入文件移动/复制到的目录，为空时保留在原位置")
+	watch := fs.Bool("watch", false, "持续监控输入目录，新文件写入完成后自动提交，直到按 Ctrl+C 停止")
+	watchInterval := fs.Duration("watch-interval", 2*time.Second, "监控模式下扫描输入目录的间隔")
+	settle := fs.Duration("settle", 3*time.Second, "监控模式下文件大小保持不变多久后视为写入完成")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if code, ok := workflowArg(fs, workflowID); !ok {
+		return code
+	}
+	inputKinds, err := api.ParseInputKinds(*kinds)
+	if err != nil {
+		return usageError(fs, "参数错误: %v", err)
+	}
+	mode, err := api.ParseDispositionMode(*disposition)
+	if err != nil {
+		return usageError(fs, "参数错误: %v", err)
+	}
+	inputOpts := api.InputOptions{
+		Dir:       *inputDir,
+		Recursive: *recursive,
+		Include:   includes,
+		Exclude:   excludes,
+		Kinds:     inputKinds,
+		SortBy:    *sortBy,
+		Reverse:   *reverse,
+		Limit:     *limit,
+	}
+	dispositionOpts := api.Disposition{
+		Mode:      mode,
+		DoneDir:   *doneDir,
+		FailedDir: *failedDir,
+	}
+
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	if *watch {
+		// 按 Ctrl+C 中断执行器后停止监控，执行中的任务按 -on-interrupt 处理
+		run, err := api.Watch(*workflowID, api.WatchOptions{
+			Concurrency: *concurrency,
+			Inputs:      inputOpts,
+			Disposition: dispositionOpts,
+			Interval:    *watchInterval,
+			Settle:      *settle,
+		}, executor, nil)
+		if err != nil {
+			return fail("监控输入目录失败: %v", err)
+		}
+		return reportOpts.finish(run)
+	}
+
+	run, err := api.BatchProcessInputsWithOptions(*workflowID, api.BatchOptions{
+		Concurrency: *concurrency,
+		Inputs:      inputOpts,
+		Disposition: dispositionOpts,
+	}, executor)
+	if err != nil {
+		return fail("批量处理失败: %v", err)
+	}
+	return reportOpts.finish(run)
+}
+
+func runBatchText(fs *flag.FlagSet, args []string) int {
+	workflowID := fs.String("workflow", "", "要执行的工作流ID，也可以作为位置参数指定")
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	textFile := fs.String("text-file", "doc/book.txt", "批量文本处理的文本文件")
+	split := fs.String("split", api.SplitLine, "文本拆分方式: line, paragraph, sentence, chunk")
+	maxChars := fs.Int("max-chars", 500, "chunk 拆分方式下每段的最大字符数")
+	template := fs.String("template", api.TextPlaceholder, "提示词模板，{text} 会被替换为每段文本，如 \"Realistic style, {text}\"")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if code, ok := workflowArg(fs, workflowID); !ok {
+		return code
+	}
+
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	run, err := api.BatchProcessText(*workflowID, api.TextOptions{
+		File:        *textFile,
+		Split:       *split,
+		MaxChars:    *maxChars,
+		Template:    *template,
+		Concurrency: *concurrency,
+	}, executor)
+	if err != nil {
+		return fail("批量文本处理失败: %v", err)
+	}
+	return reportOpts.finish(run)
+}
+
+func runBatchManifest(fs *flag.FlagSet, args []string) int {
+	workflowID := fs.String("workflow", "", "清单中未指定工作流的行使用的默认工作流ID")
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	manifest, code, ok := fileArg(fs, "清单文件")
+	if !ok {
+		return code
+	}
+
+	jobs, err := api.LoadManifest(manifest, *workflowID)
+	if err != nil {
+		return fail("读取清单失败: %v", err)
+	}
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	run, err := api.BatchProcessManifest(jobs, *concurrency, executor)
+	if err != nil {
+		return fail("清单处理失败: %v", err)
+	}
+	return reportOpts.finish(run)
+}
+
+func runBatchSweep(fs *flag.FlagSet, args []string) int {
+	workflowID := fs.String("workflow", "", "扫描配置中未指定工作流时使用的工作流ID")
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	specPath, code, ok := fileArg(fs, "扫描配置文件")
+	if !ok {
+		return code
+	}
+
+	spec, err := api.LoadSweepSpec(specPath)
+	if err != nil {
+		return fail("读取扫描配置失败: %v", err)
+	}
+	if spec.WorkflowID == "" {
+		spec.WorkflowID = *workflowID
+	}
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	index, err := api.RunSweep(spec, *concurrency, executor)
+	if err != nil {
+		return fail("参数扫描失败: %v", err)
+	}
+	return reportOpts.finish(index.Run)
+}
+
+func runBatchPipeline(fs *flag.FlagSet, args []string) int {
+	imagePath := fs.String("image", "", "覆盖流水线配置中的 image 输入")
+	videoPath := fs.String("video", "", "覆盖流水线配置中的 video 输入")
+	audioPath := fs.String("audio", "", "覆盖流水线配置中的 audio 输入")
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	pipelinePath, code, ok := fileArg(fs, "流水线配置文件")
+	if !ok {
+		return code
+	}
+
+	// -image/-video/-audio 覆盖流水线配置中的同名输入
+	p, err := api.LoadPipeline(pipelinePath, map[string]string{
+		"image": *imagePath,
+		"video": *videoPath,
+		"audio": *audioPath,
+	})
+	if err != nil {
+		return fail("读取流水线配置失败: %v", err)
+	}
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	run, err := api.RunPipeline(p, *concurrency, executor)
+	if err != nil {
+		return fail("流水线执行失败: %v", err)
+	}
+	return reportOpts.finish(run)
+}
+
+func runBatchResume(fs *flag.FlagSet, args []string) int {
+	concurrency := fs.Int("concurrency", 1, "并发数量")
+	var execOpts executorOptions
+	execOpts.register(fs, true)
+	var reportOpts reportOptions
+	reportOpts.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	recordPath, code, ok := fileArg(fs, "运行记录文件")
+	if !ok {
+		return code
+	}
+
+	run, err := api.LoadRunRecord(recordPath)
+	if err != nil {
+		return fail("恢复运行失败: %v", err)
+	}
+	// 运行记录保存在输出目录下，按记录所在位置确定输出目录，便于整个目录被移动后恢复
+	run.OutputDir = filepath.Dir(recordPath)
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	api.ResumeRun(run, *concurrency, executor)
+	return reportOpts.finish(run)
+}
+
+func runReport(fs *flag.FlagSet, args []string) int {
+	markdown := fs.Bool("md", false, "同时生成 Markdown 摘要")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	recordPath, code, ok := fileArg(fs, "运行记录文件")
+	if !ok {
+		return code
+	}
+	run, err := api.LoadRunRecord(recordPath)
+	if err != nil {
+		return fail("生成报告失败: %v", err)
+	}
+	writeReport(run, true, *markdown)
+	return exitOK
+}
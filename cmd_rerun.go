@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+
+	"runninghub/api"
+)
+
+// rerunCommand 用登记的节点参数重新提交任务
+var rerunCommand = &command{
+	name:    "rerun",
+	args:    "<任务ID>",
+	summary: "用任务登记表中记录的节点参数（包括随机种子）重新提交任务，等待完成并下载结果",
+	run:     runRerun,
+}
+
+func runRerun(fs *flag.FlagSet, args []string) int {
+	var execOpts executorOptions
+	execOpts.register(fs, false)
+	execOpts.registerInterrupt(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "必须指定一个任务ID")
+	}
+
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+	registry := api.GetTaskRegistry()
+	if registry == nil {
+		return fail("任务登记表不可用")
+	}
+	original, exists, err := registry.Get(fs.Arg(0))
+	if err != nil {
+		return fail("%v", err)
+	}
+	if !exists {
+		return fail("任务登记表中没有该任务: %s", fs.Arg(0))
+	}
+	rememberProfile(original)
+
+	human("用任务 %s 的节点参数重新提交\n", original.TaskID)
+	// 有输入文件时用输入文件名命名结果，与原任务一致
+	baseName := ""
+	if len(original.Inputs) > 0 {
+		baseName = fileBaseName(original.Inputs[0])
+	}
+	return waitTask(executor, api.TaskOptions{
+		Label:    original.Label,
+		BaseName: baseName,
+		Inputs:   original.Inputs,
+		RerunOf:  original.TaskID,
+		Create: func() (*api.TaskCreateResponse, error) {
+			return api.RerunTask(original)
+		},
+	})
+}
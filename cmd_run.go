@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"runninghub/api"
+)
+
+// runCommand 单次执行工作流
+var runCommand = &command{
+	name:    "run",
+	args:    "[工作流ID]",
+	summary: "执行一次工作流，等待完成并下载结果到 outputs/<日期>/",
+	run:     runRun,
+}
+
+func runRun(fs *flag.FlagSet, args []string) int {
+	workflowID := fs.String("workflow", "", "要执行的工作流ID，也可以作为位置参数指定")
+	imagePath := fs.String("image", "", "要上传的图片（或视频）路径")
+	videoPath := fs.String("video", "", "要上传的视频路径，与 -audio 一起使用")
+	audioPath := fs.String("audio", "", "要上传的音频路径，与 -video 一起使用")
+	var execOpts executorOptions
+	execOpts.register(fs, false)
+	execOpts.registerInterrupt(fs)
+	execOpts.registerSeed(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if code, ok := workflowArg(fs, workflowID); !ok {
+		return code
+	}
+	if (*videoPath == "") != (*audioPath == "") {
+		return usageError(fs, "-video 和 -audio 必须同时指定")
+	}
+
+	executor, err := execOpts.newExecutor()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	opts := api.TaskOptions{}
+	if *videoPath != "" && *audioPath != "" {
+		// 执行带视频和音频的工作流，使用视频文件名作为基础名
+		opts.Inputs = []string{*videoPath, *audioPath}
+		opts.BaseName = fileBaseName(*videoPath)
+		opts.Create = func() (*api.TaskCreateResponse, error) {
+			return executor.ExecuteWorkflowWithVideoAndAudio(*workflowID, *videoPath, *audioPath)
+		}
+	} else if *imagePath != "" {
+		// 执行带图片的工作流，使用图片文件名作为基础名
+		opts.Inputs = []string{*imagePath}
+		opts.BaseName = fileBaseName(*imagePath)
+		opts.Create = func() (*api.TaskCreateResponse, error) {
+			return executor.ExecuteWorkflowWithImage(*workflowID, *imagePath)
+		}
+	} else {
+		// 执行普通工作流
+		opts.Create = func() (*api.TaskCreateResponse, error) {
+			return executor.ExecuteWorkflow(*workflowID)
+		}
+	}
+	if len(opts.Inputs) > 0 {
+		opts.Label = opts.Inputs[0]
+	}
+	return waitTask(executor, opts)
+}
+
+// fileBaseName 返回不含扩展名的文件名
+func fileBaseName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// waitTask 创建任务、等待完成并把结果下载到 outputs/<日期>/，返回退出码
+// 与批量处理使用同一流程（重试、超时、通知和任务登记），文件名前缀 BaseName 为空时使用任务ID
+func waitTask(executor *api.WorkflowExecutor, opts api.TaskOptions) int {
+	opts.OnCreated = func(result *api.TaskResult) {
+		if len(result.Attempts) > 0 {
+			human("任务已重新提交（第 %d 次），新任务ID: %s\n", len(result.Attempts), result.TaskID)
+		} else {
+			human("任务创建成功！任务ID: %s\n", result.TaskID)
+		}
+		if result.Seed != nil {
+			human("随机种子: %d\n", *result.Seed)
+		}
+		human("正在等待任务完成...\n")
+	}
+	result := executor.RunTask(opts)
+
+	doc := taskDocument{
+		TaskID:     result.TaskID,
+		WorkflowID: result.WorkflowID,
+		Status:     result.Status,
+		Error:      result.Error,
+		Outputs:    result.Outputs,
+		Skipped:    result.Skipped,
+		Files:      result.Files,
+		Hooks:      result.Hooks,
+	}
+	if !result.Succeeded() {
+		code := taskResultCode(result)
+		if global.json {
+			printJSON(doc)
+		} else if result.TaskID == "" {
+			fmt.Fprintf(os.Stderr, "执行工作流失败: %s\n", result.Error)
+		} else {
+			fmt.Fprintln(os.Stderr, result.Error)
+		}
+		return code
+	}
+	if global.json {
+		printJSON(doc)
+		return exitOK
+	}
+	human("\n任务执行成功！\n")
+	human("生成结果:\n")
+	if doc.Skipped > 0 {
+		human("按筛选规则跳过 %d 个输出\n", doc.Skipped)
+	}
+	for _, output := range result.Outputs {
+		printOutput(output)
+	}
+	for _, file := range result.Files {
+		human("  已保存到: %s\n", file)
+	}
+	return exitOK
+}
+
+// taskResultCode 根据未成功任务的结果确定退出码
+func taskResultCode(result *api.TaskResult) int {
+	switch {
+	case result.Interrupted():
+		return exitInterrupted
+	case result.Reason == api.ReasonAuth:
+		return exitAuth
+	case result.Reason == api.ReasonBudget:
+		return exitBudget
+	}
+	return exitFailure
+}
+
+// human 非 -json 模式下输出提示信息
+func human(format string, args ...any) {
+	if !global.json {
+		fmt.Printf(format, args...)
+	}
+}
+
+// printOutput 非 -json 模式下打印一个任务输出
+func printOutput(output api.TaskOutput) {
+	human("- 文件URL: %s\n", output.FileUrl)
+	human("  类型: %s\n", output.FileType)
+	human("  节点ID: %s\n", output.NodeId)
+	human("  任务耗时: %s\n", output.TaskCostTime)
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"runninghub/api"
+)
+
+// taskCommand 管理已创建的任务
+var taskCommand = &command{
+	name:    "task",
+	summary: "管理已创建的任务: status、outputs、cancel、wait、list、refresh、cancel-all、download、purge",
+	children: []*command{
+		{name: "status", args: "<任务ID>", summary: "查询一次任务状态（QUEUED、RUNNING、FAILED、SUCCESS）", run: runTaskStatus},
+		{name: "outputs", args: "<任务ID>", summary: "查询已完成任务的生成结果，加 -download 下载到本地", run: runTaskOutputs},
+		{name: "cancel", args: "<任务ID>", summary: "取消排队中或运行中的任务", run: runTaskCancel},
+		{name: "wait", args: "<任务ID>", summary: "等待任务结束并打印生成结果，任务失败时退出码非 0", run: runTaskWait},
+		{name: "list", summary: "列出任务登记表中本工具创建的任务，可按状态、工作流和日期筛选", run: runTaskList},
+		{name: "refresh", summary: "查询登记表中未结束任务的最新状态", run: runTaskRefresh},
+		{name: "cancel-all", summary: "取消登记表中所有排队中和运行中的任务", run: runTaskCancelAll},
+		{name: "download", summary: "重新下载登记表中已成功任务的生成结果", run: runTaskDownload},
+		{name: "purge", summary: "从登记表中删除旧的任务记录（不影响服务器上的任务和已下载的文件）", run: runTaskPurge},
+	},
+}
+
+// taskArg 解析参数并返回唯一的任务ID，然后设置 API Key，ok 为 false 时应直接返回 code
+// 任务在登记表中时同时返回登记的记录，否则 record 为 nil
+func taskArg(fs *flag.FlagSet, args []string) (taskID string, record *api.TaskRecord, code int, ok bool) {
+	if code, ok := parseFlags(fs, args); !ok {
+		return "", nil, code, false
+	}
+	if fs.NArg() != 1 {
+		return "", nil, usageError(fs, "必须指定一个任务ID"), false
+	}
+	if err := setupApiKey(); err != nil {
+		return "", nil, fail("%v", err), false
+	}
+	taskID = fs.Arg(0)
+	if registry := api.GetTaskRegistry(); registry != nil {
+		found, exists, err := registry.Get(taskID)
+		if err != nil {
+			logger.Warn("读取任务登记表失败", "error", err)
+		} else if exists {
+			record = found
+			rememberProfile(record)
+		}
+	}
+	return taskID, record, exitOK, true
+}
+
+// rememberProfile 任务由某个 profile 创建时，之后对该任务的请求使用该 profile 的 API Key
+func rememberProfile(record *api.TaskRecord) {
+	if record.Profile == "" {
+		return
+	}
+	apiKey, _, err := config.ResolveApiKey(record.Profile)
+	if err != nil {
+		logger.Warn("找不到创建任务的 profile，使用当前 API Key", "taskId", record.TaskID, "profile", record.Profile, "error", err)
+		return
+	}
+	api.RememberTaskKey(record.TaskID, apiKey)
+}
+
+// updateRecord 更新登记表中任务的状态，record 为 nil 时不做任何事
+func updateRecord(record *api.TaskRecord, status, errMsg string) {
+	if record == nil {
+		return
+	}
+	record.Status, record.Error = status, errMsg
+	api.RecordTask(record)
+}
+
+func runTaskStatus(fs *flag.FlagSet, args []string) int {
+	taskID, record, code, ok := taskArg(fs, args)
+	if !ok {
+		return code
+	}
+	resp, err := api.QueryTaskStatus(taskID)
+	if err != nil {
+		return fail("查询任务状态失败: %v", err)
+	}
+	if resp.Code != 0 {
+		return fail("查询任务状态失败: %v", responseError(resp.Code, resp.Msg))
+	}
+	updateRecord(record, resp.Data, "")
+	if global.json {
+		printJSON(taskDocument{TaskID: taskID, Status: resp.Data})
+	} else {
+		fmt.Printf("任务 %s 状态: %s\n", taskID, resp.Data)
+	}
+	return exitOK
+}
+
+func runTaskOutputs(fs *flag.FlagSet, args []string) int {
+	download := fs.Bool("download", false, "下载生成结果，命名和任务日志与新任务相同")
+	outputDir := fs.String("output-dir", "", "与 -download 一起使用，保存到该目录，默认为登记的输出目录或 outputs/<日期>/")
+	workflowID := fs.String("workflow", "", "与 -download 一起使用，按该工作流的输出筛选规则保存，默认为登记的工作流")
+	taskID, record, code, ok := taskArg(fs, args)
+	if !ok {
+		return code
+	}
+	if *download {
+		return downloadTaskOutputs(taskID, record, *workflowID, *outputDir)
+	}
+	resp, err := api.QueryTaskOutputs(taskID)
+	if err != nil {
+		return fail("查询任务结果失败: %v", err)
+	}
+	if resp.Code != 0 {
+		// 任务未完成或已失败时接口返回非 0 的 code
+		return fail("查询任务结果失败: %v", responseError(resp.Code, resp.Msg))
+	}
+	if global.json {
+		printJSON(taskDocument{TaskID: taskID, Status: "SUCCESS", Outputs: resp.Data})
+		return exitOK
+	}
+	fmt.Printf("任务 %s 的生成结果:\n", taskID)
+	for _, output := range resp.Data {
+		printOutput(output)
+	}
+	return exitOK
+}
+
+// downloadTaskOutputs 下载任务的生成结果，任务在登记表中时使用登记的工作流、输出目录和文件名前缀，并更新记录
+func downloadTaskOutputs(taskID string, record *api.TaskRecord, workflowID, outputDir string) int {
+	baseName := ""
+	var seed *int64
+	if record != nil {
+		if workflowID == "" {
+			workflowID = record.WorkflowID
+		}
+		if outputDir == "" {
+			outputDir = record.OutputDir
+		}
+		baseName = record.BaseName
+		seed = record.Seed
+	}
+	executor := api.NewWorkflowExecutor(newManager())
+	result, err := executor.DownloadTaskOutputs(taskID, workflowID, outputDir, baseName, seed)
+	if result == nil {
+		return fail("下载任务结果失败: %v", err)
+	}
+	doc := taskDocument{TaskID: taskID, WorkflowID: workflowID, Status: "SUCCESS", Outputs: result.Outputs, Skipped: result.Skipped, Files: result.Files}
+	if record != nil {
+		record.Files = result.Files
+		updateRecord(record, "SUCCESS", "")
+	}
+	if !global.json {
+		fmt.Printf("任务 %s 的生成结果:\n", taskID)
+		if doc.Skipped > 0 {
+			fmt.Printf("按筛选规则跳过 %d 个输出\n", doc.Skipped)
+		}
+		for _, output := range doc.Outputs {
+			printOutput(output)
+		}
+		for _, file := range doc.Files {
+			fmt.Printf("已保存到: %s\n", file)
+		}
+	}
+	if err != nil {
+		doc.Error = err.Error()
+		if global.json {
+			printJSON(doc)
+			return exitFailure
+		}
+		return fail("下载任务结果失败: %v", err)
+	}
+	if global.json {
+		printJSON(doc)
+	}
+	return exitOK
+}
+
+func runTaskCancel(fs *flag.FlagSet, args []string) int {
+	taskID, record, code, ok := taskArg(fs, args)
+	if !ok {
+		return code
+	}
+	resp, err := api.CancelTask(taskID)
+	if err != nil {
+		return fail("取消任务失败: %v", err)
+	}
+	if resp.Code != 0 {
+		return fail("取消任务失败: %v", responseError(resp.Code, resp.Msg))
+	}
+	updateRecord(record, api.TaskStatusCanceled, "")
+	if global.json {
+		printJSON(taskDocument{TaskID: taskID, Status: "CANCELED"})
+	} else {
+		fmt.Printf("任务 %s 已取消\n", taskID)
+	}
+	return exitOK
+}
+
+func runTaskWait(fs *flag.FlagSet, args []string) int {
+	taskID, record, code, ok := taskArg(fs, args)
+	if !ok {
+		return code
+	}
+	executor := api.NewWorkflowExecutor(newManager())
+	doc := taskDocument{TaskID: taskID, Status: "SUCCESS"}
+	err := executor.MonitorTask(taskID, func(outputResp *api.TaskOutputResponse) {
+		doc.Outputs = outputResp.Data
+		human("\n任务执行成功！\n")
+		human("生成结果:\n")
+		for _, output := range outputResp.Data {
+			printOutput(output)
+		}
+	})
+	if err != nil {
+		updateRecord(record, taskErrorStatus(err), err.Error())
+		return doc.monitorFailed(err)
+	}
+	updateRecord(record, "SUCCESS", "")
+	if global.json {
+		printJSON(doc)
+	}
+	return exitOK
+}
+
+// taskFilterOptions 任务登记表的筛选参数
+type taskFilterOptions struct {
+	statuses listFlag
+	workflow string
+	since    string
+	until    string
+}
+
+// register 注册参数
+func (o *taskFilterOptions) register(fs *flag.FlagSet) {
+	fs.Var(&o.statuses, "status", "只处理这些状态的任务，如 RUNNING 或 FAILED,ERROR，可重复指定或用逗号分隔")
+	fs.StringVar(&o.workflow, "workflow", "", "只处理该工作流的任务")
+	fs.StringVar(&o.since, "since", "", "只处理此后创建的任务，日期（2006-01-02 或 \"2006-01-02 15:04\"）或距今时长（如 24h、7d）")
+	fs.StringVar(&o.until, "until", "", "只处理此前创建的任务，格式同 -since")
+}
+
+// filter 返回筛选条件
+func (o *taskFilterOptions) filter() (api.TaskFilter, error) {
+	filter := api.TaskFilter{Statuses: o.statuses, WorkflowID: o.workflow}
+	var err error
+	if filter.Since, err = parseTimeArg(o.since); err != nil {
+		return filter, fmt.Errorf("-since: %v", err)
+	}
+	if filter.Until, err = parseTimeArg(o.until); err != nil {
+		return filter, fmt.Errorf("-until: %v", err)
+	}
+	return filter, nil
+}
+
+// parseTimeArg 解析日期或距今时长，空字符串返回零值
+func parseTimeArg(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := parseAge(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的时间: %s", s)
+}
+
+// parseAge 解析时长，除 time.ParseDuration 支持的格式外，还支持 7d 表示 7 天
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无效的时长: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// listRecords 解析筛选参数并返回登记表中满足条件的任务，needApiKey 为 true 时同时设置 API Key
+// ok 为 false 时应直接返回 code
+func listRecords(fs *flag.FlagSet, args []string, opts *taskFilterOptions, needApiKey bool) (records []*api.TaskRecord, code int, ok bool) {
+	if code, ok := parseFlags(fs, args); !ok {
+		return nil, code, false
+	}
+	if fs.NArg() != 0 {
+		return nil, usageError(fs, "参数过多: %s", strings.Join(fs.Args(), " ")), false
+	}
+	filter, err := opts.filter()
+	if err != nil {
+		return nil, usageError(fs, "参数错误: %v", err), false
+	}
+	if needApiKey {
+		if err := setupApiKey(); err != nil {
+			return nil, fail("%v", err), false
+		}
+	} else {
+		setupRegistry()
+	}
+	registry := api.GetTaskRegistry()
+	if registry == nil {
+		return nil, fail("任务登记表不可用"), false
+	}
+	records, err = registry.List(filter)
+	if err != nil {
+		return nil, fail("%v", err), false
+	}
+	return records, exitOK, true
+}
+
+func runTaskList(fs *flag.FlagSet, args []string) int {
+	var opts taskFilterOptions
+	opts.register(fs)
+	records, code, ok := listRecords(fs, args, &opts, false)
+	if !ok {
+		return code
+	}
+	if global.json {
+		if records == nil {
+			records = []*api.TaskRecord{}
+		}
+		printJSON(records)
+		return exitOK
+	}
+	if len(records) == 0 {
+		fmt.Println("没有符合条件的任务")
+		return exitOK
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "任务ID\t状态\t工作流ID\t创建时间\t账户\t标识")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.TaskID, r.Status, orDash(r.WorkflowID), r.Created.Local().Format("2006-01-02 15:04:05"), orDash(r.Profile), orDash(recordLabel(r)))
+	}
+	w.Flush()
+	return exitOK
+}
+
+// orDash 空字符串显示为 -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// recordLabel 返回任务的标识，未记录时使用第一个输入文件
+func recordLabel(r *api.TaskRecord) string {
+	if r.Label == "" && len(r.Inputs) > 0 {
+		return r.Inputs[0]
+	}
+	return r.Label
+}
+
+// bulkResult 批量操作处理过的任务和出错的数量
+type bulkResult struct {
+	records []*api.TaskRecord
+	failed  int
+}
+
+// done 输出批量操作的结果并返回退出码，summary 中的 %d 为成功处理的数量
+// -json 模式下输出处理过的任务
+func (b *bulkResult) done(summary string) int {
+	if global.json {
+		if b.records == nil {
+			b.records = []*api.TaskRecord{}
+		}
+		printJSON(b.records)
+	} else {
+		fmt.Printf(summary+"，出错 %d 个\n", len(b.records)-b.failed, b.failed)
+	}
+	if b.failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runTaskRefresh(fs *flag.FlagSet, args []string) int {
+	var opts taskFilterOptions
+	opts.register(fs)
+	records, code, ok := listRecords(fs, args, &opts, true)
+	if !ok {
+		return code
+	}
+	var result bulkResult
+	for _, r := range records {
+		if r.Finished() {
+			continue
+		}
+		result.records = append(result.records, r)
+		rememberProfile(r)
+		resp, err := api.QueryTaskStatus(r.TaskID)
+		if err == nil && resp.Code != 0 {
+			err = responseError(resp.Code, resp.Msg)
+		}
+		if err != nil {
+			logger.Error("查询任务状态失败", "taskId", r.TaskID, "error", err)
+			result.failed++
+			continue
+		}
+		if resp.Data != r.Status {
+			human("%s  %s -> %s\n", r.TaskID, r.Status, resp.Data)
+			updateRecord(r, resp.Data, "")
+		}
+	}
+	return result.done("已刷新 %d 个任务")
+}
+
+func runTaskCancelAll(fs *flag.FlagSet, args []string) int {
+	var opts taskFilterOptions
+	opts.register(fs)
+	records, code, ok := listRecords(fs, args, &opts, true)
+	if !ok {
+		return code
+	}
+	var result bulkResult
+	for _, r := range records {
+		if r.Status != "QUEUED" && r.Status != "RUNNING" {
+			continue
+		}
+		result.records = append(result.records, r)
+		rememberProfile(r)
+		resp, err := api.CancelTask(r.TaskID)
+		if err == nil && resp.Code != 0 {
+			err = responseError(resp.Code, resp.Msg)
+		}
+		if err != nil {
+			logger.Error("取消任务失败", "taskId", r.TaskID, "error", err)
+			result.failed++
+			continue
+		}
+		human("任务 %s 已取消\n", r.TaskID)
+		updateRecord(r, api.TaskStatusCanceled, "")
+	}
+	return result.done("已取消 %d 个任务")
+}
+
+func runTaskDownload(fs *flag.FlagSet, args []string) int {
+	var opts taskFilterOptions
+	opts.register(fs)
+	outputDir := fs.String("output-dir", "", "保存到该目录，默认保存到任务原来的输出目录")
+	records, code, ok := listRecords(fs, args, &opts, true)
+	if !ok {
+		return code
+	}
+	executor := api.NewWorkflowExecutor(newManager())
+	var result bulkResult
+	for _, r := range records {
+		if r.Status != "SUCCESS" {
+			continue
+		}
+		result.records = append(result.records, r)
+		rememberProfile(r)
+		dir := *outputDir
+		if dir == "" {
+			dir = r.OutputDir
+		}
+		if dir == "" {
+			dir = api.CreateOutputDir()
+		}
+		if err := downloadRecord(r, dir, executor); err != nil {
+			logger.Error("下载任务结果失败", "taskId", r.TaskID, "error", err)
+			result.failed++
+			continue
+		}
+		human("任务 %s 的 %d 个文件已保存到 %s\n", r.TaskID, len(r.Files), filepath.Clean(dir))
+		api.RecordTask(r)
+	}
+	return result.done("已下载 %d 个任务")
+}
+
+// downloadRecord 下载任务的生成结果到 dir，并把保存的文件写入记录
+func downloadRecord(r *api.TaskRecord, dir string, executor *api.WorkflowExecutor) error {
+	result, err := executor.DownloadTaskOutputs(r.TaskID, r.WorkflowID, dir, r.BaseName, r.Seed)
+	if result != nil {
+		r.Files = result.Files
+	}
+	return err
+}
+
+func runTaskPurge(fs *flag.FlagSet, args []string) int {
+	var opts taskFilterOptions
+	opts.register(fs)
+	olderThan := fs.String("older-than", "30d", "删除创建时间早于该时长的记录，如 720h、30d；0 表示不限创建时间")
+	all := fs.Bool("all", false, "同时删除未结束的任务（排队中、执行中、本地出错等），默认只删除已结束的任务")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 0 {
+		return usageError(fs, "参数过多: %s", strings.Join(fs.Args(), " "))
+	}
+	filter, err := opts.filter()
+	if err != nil {
+		return usageError(fs, "参数错误: %v", err)
+	}
+	// 未结束任务的记录是 task cancel/download 唯一的本地依据，未指定 -status 或 -all 时保留
+	filter.Finished = len(filter.Statuses) == 0 && !*all
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return usageError(fs, "参数错误: -older-than: %v", err)
+	}
+	if age > 0 {
+		if cutoff := time.Now().Add(-age); filter.Until.IsZero() || cutoff.Before(filter.Until) {
+			filter.Until = cutoff
+		}
+	}
+	setupRegistry()
+	registry := api.GetTaskRegistry()
+	if registry == nil {
+		return fail("任务登记表不可用")
+	}
+	n, err := registry.Purge(filter)
+	if err != nil {
+		return fail("清理任务登记表失败: %v", err)
+	}
+	if global.json {
+		printJSON(map[string]int{"purged": n})
+	} else {
+		fmt.Printf("已从登记表中删除 %d 个任务\n", n)
+	}
+	return exitOK
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"runninghub/api"
+)
+
+// workflowsCommand 查看已注册的工作流，不需要 API Key
+var workflowsCommand = &command{
+	name:    "workflows",
+	summary: "查看已注册的工作流: list、show、inspect",
+	children: []*command{
+		{name: "list", summary: "列出所有工作流的ID、名称和输入类型", run: runWorkflowsList},
+		{name: "show", args: "<工作流ID>", summary: "显示工作流的描述和节点配置", run: runWorkflowsShow},
+		{name: "inspect", args: "<工作流ID>", summary: "以 JSON 输出工作流的完整配置", run: runWorkflowsInspect},
+	},
+}
+
+func runWorkflowsList(fs *flag.FlagSet, args []string) int {
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 0 {
+		return usageError(fs, "参数过多: %s", strings.Join(fs.Args(), " "))
+	}
+	workflows := newManager().ListWorkflows()
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].ID < workflows[j].ID })
+	if global.json {
+		summaries := make([]workflowSummary, 0, len(workflows))
+		for _, wf := range workflows {
+			summaries = append(summaries, workflowSummary{ID: wf.ID, Name: wf.Name, Description: wf.Description, Inputs: wf.InputKinds()})
+		}
+		printJSON(summaries)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "工作流ID\t名称\t输入")
+	for _, wf := range workflows {
+		var kinds []string
+		for _, kind := range wf.InputKinds() {
+			kinds = append(kinds, string(kind))
+		}
+		input := strings.Join(kinds, ",")
+		if input == "" {
+			input = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", wf.ID, wf.Name, input)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// workflowSummary -json 模式下 workflows list 的一项
+type workflowSummary struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Inputs      []api.InputKind `json:"inputs"` // 接受的输入文件类型，纯文本工作流为空
+}
+
+// workflowArgOnly 返回唯一的位置参数指定的工作流，ok 为 false 时应直接返回 code
+func workflowArgOnly(fs *flag.FlagSet, args []string) (wf *api.WorkflowConfig, code int, ok bool) {
+	if code, ok := parseFlags(fs, args); !ok {
+		return nil, code, false
+	}
+	if fs.NArg() != 1 {
+		return nil, usageError(fs, "必须指定一个工作流ID"), false
+	}
+	wf, exists := newManager().GetWorkflow(fs.Arg(0))
+	if !exists {
+		return nil, fail("工作流不存在: %s（运行 '%s workflows list' 查看所有工作流）", fs.Arg(0), programName), false
+	}
+	return wf, exitOK, true
+}
+
+func runWorkflowsShow(fs *flag.FlagSet, args []string) int {
+	wf, code, ok := workflowArgOnly(fs, args)
+	if !ok {
+		return code
+	}
+	if global.json {
+		// -json 模式下与 inspect 相同
+		printJSON(wf)
+		return exitOK
+	}
+	fmt.Printf("工作流ID: %s\n", wf.ID)
+	fmt.Printf("名称: %s\n", wf.Name)
+	fmt.Printf("描述: %s\n", wf.Description)
+	fmt.Println("节点配置:")
+	for _, param := range wf.Params {
+		desc := wf.NodeConfigs[param.NodeId]
+		if desc != "" {
+			desc = "（" + desc + "）"
+		}
+		if kind := param.InputKind(); kind != "" {
+			fmt.Printf("  - %s输入节点: %s%s\n", kind, param.NodeId, desc)
+		} else {
+			fmt.Printf("  - 节点: %s%s, 字段: %s, 值: %v\n", param.NodeId, desc, param.FieldName, param.FieldValue)
+		}
+	}
+	if !wf.Outputs.IsZero() {
+		fmt.Printf("输出筛选: 节点 %v, 类型 %v, 最后 %d 个\n", wf.Outputs.NodeIds, wf.Outputs.FileTypes, wf.Outputs.Last)
+	}
+	return exitOK
+}
+
+func runWorkflowsInspect(fs *flag.FlagSet, args []string) int {
+	wf, code, ok := workflowArgOnly(fs, args)
+	if !ok {
+		return code
+	}
+	if global.json {
+		printJSON(wf)
+		return exitOK
+	}
+	data, err := json.MarshalIndent(wf, "", "  ")
+	if err != nil {
+		return fail("序列化工作流配置失败: %v", err)
+	}
+	fmt.Println(string(data))
+	return exitOK
+}
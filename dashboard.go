@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"runninghub/api"
+)
+
+// dashboardMaxRows 面板最多显示的执行中任务数，超出部分合并为一行
+const dashboardMaxRows = 20
+
+// dashboardLabelWidth 任务标识列的显示宽度
+const dashboardLabelWidth = 32
+
+// defaultTerminalWidth 无法得知终端宽度时假定的列数
+const defaultTerminalWidth = 80
+
+// dashboard 批量运行的终端进度面板，每个执行中的任务一行，最后一行显示总体计数、完成百分比和预计剩余时间
+// 同时作为日志输出，日志显示在面板上方，不会和面板交错
+// 每行截断到终端宽度，避免自动换行后重绘时清除的行数不对
+type dashboard struct {
+	out io.Writer
+
+	mu        sync.Mutex
+	total     int // 任务总数，0 表示事先未知
+	started   time.Time
+	rows      []*dashboardRow // 执行中的任务，按开始顺序排列
+	succeeded int
+	failed    int
+	canceled  int  // 因运行被中断而取消的任务数
+	lines     int  // 上次绘制的行数，重绘前清除
+	running   bool // RunStarted 之后、RunFinished 之前为 true
+	stop      chan struct{}
+}
+
+// dashboardRow 一个执行中的任务
+type dashboardRow struct {
+	label   string
+	taskID  string
+	status  string
+	started time.Time
+}
+
+// newDashboard 创建输出到 out 的进度面板，out 应为终端
+func newDashboard(out io.Writer) *dashboard {
+	return &dashboard{out: out}
+}
+
+// isTerminal 判断文件是否为终端
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth 返回面板输出的终端列数，无法查询时依次使用 COLUMNS 环境变量和 defaultTerminalWidth
+func (d *dashboard) terminalWidth() int {
+	if f, ok := d.out.(*os.File); ok {
+		if cols := ttyColumns(f); cols > 0 {
+			return cols
+		}
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return defaultTerminalWidth
+}
+
+// RunStarted 记录任务总数并开始定时刷新耗时
+func (d *dashboard) RunStarted(run *api.RunRecord, total int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total = total
+	d.started = time.Now()
+	d.running = true
+	d.stop = make(chan struct{})
+	go d.tick(d.stop)
+	d.redraw()
+}
+
+// tick 每秒重绘一次，直到 stop 关闭
+func (d *dashboard) tick(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.redraw()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// TaskProgress 新增或更新任务所在的行
+func (d *dashboard) TaskProgress(progress api.TaskProgress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row := d.find(progress.Label, progress.Started)
+	if row == nil {
+		row = &dashboardRow{label: progress.Label, started: progress.Started}
+		d.rows = append(d.rows, row)
+	}
+	row.taskID, row.status = progress.TaskID, progress.Status
+	d.redraw()
+}
+
+// TaskFinished 移除任务所在的行并更新计数
+func (d *dashboard) TaskFinished(result *api.TaskResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, row := range d.rows {
+		if row.label == result.Label && row.started.Equal(result.Started) {
+			d.rows = append(d.rows[:i], d.rows[i+1:]...)
+			break
+		}
+	}
+	switch {
+	case result.Succeeded():
+		d.succeeded++
+	case result.Interrupted():
+		d.canceled++
+	default:
+		d.failed++
+	}
+	d.redraw()
+}
+
+// RunFinished 停止刷新，保留最后的计数行
+func (d *dashboard) RunFinished(run *api.RunRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.running {
+		return
+	}
+	close(d.stop)
+	d.rows = nil
+	d.redraw()
+	d.running = false
+	d.lines = 0
+}
+
+// Write 在面板上方输出日志
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	n, err := d.out.Write(p)
+	d.redraw()
+	return n, err
+}
+
+// find 返回任务所在的行，标识相同的任务按开始时间区分
+func (d *dashboard) find(label string, started time.Time) *dashboardRow {
+	for _, row := range d.rows {
+		if row.label == label && row.started.Equal(started) {
+			return row
+		}
+	}
+	return nil
+}
+
+// clear 清除上次绘制的面板，光标回到面板第一行开头
+func (d *dashboard) clear() {
+	if d.lines > 0 {
+		fmt.Fprintf(d.out, "\x1b[%dA\r\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+// redraw 清除并重新绘制面板，调用方持有 d.mu
+func (d *dashboard) redraw() {
+	if !d.running {
+		return
+	}
+	d.clear()
+	// 留出最后一列，写满一行时部分终端会提前换行
+	width := d.terminalWidth() - 1
+	var b strings.Builder
+	lines := 0
+	line := func(s string) {
+		b.WriteString(truncateWidth(s, width))
+		b.WriteString("\n")
+		lines++
+	}
+	now := time.Now()
+	for i, row := range d.rows {
+		if i == dashboardMaxRows {
+			line(fmt.Sprintf("  ... 还有 %d 个任务执行中", len(d.rows)-i))
+			break
+		}
+		taskID := row.taskID
+		if taskID == "" {
+			taskID = "-"
+		}
+		line(fmt.Sprintf("  %s  %-19s  %-10s  %s", padWidth(row.label, dashboardLabelWidth), taskID, row.status, formatElapsed(now.Sub(row.started))))
+	}
+	line(d.counters(now))
+	io.WriteString(d.out, b.String())
+	d.lines = lines
+}
+
+// counters 返回总体计数行
+func (d *dashboard) counters(now time.Time) string {
+	done := d.succeeded + d.failed + d.canceled
+	elapsed := now.Sub(d.started)
+	progress := fmt.Sprintf("完成 %d", done)
+	if d.total > 0 {
+		progress = fmt.Sprintf("完成 %d/%d (%d%%)", done, d.total, done*100/d.total)
+	}
+	line := fmt.Sprintf("%s  成功 %d  失败 %d", progress, d.succeeded, d.failed)
+	if d.canceled > 0 {
+		line += fmt.Sprintf("  已取消 %d", d.canceled)
+	}
+	line += fmt.Sprintf("  执行中 %d  已用 %s", len(d.rows), formatElapsed(elapsed))
+	// 按已完成任务的平均速度估算剩余时间
+	if d.total > 0 && done > 0 && done < d.total {
+		eta := elapsed / time.Duration(done) * time.Duration(d.total-done)
+		line += "  预计剩余 " + formatElapsed(eta)
+	}
+	return line
+}
+
+// formatElapsed 格式化耗时，如 1:05 或 1:02:03
+func formatElapsed(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// padWidth 把 s 截断或补齐到 width 个显示宽度，中文等宽字符按 2 计算，超长时保留末尾
+func padWidth(s string, width int) string {
+	runes := []rune(s)
+	w := 0
+	start := len(runes)
+	for start > 0 && w+runeWidth(runes[start-1]) <= width {
+		start--
+		w += runeWidth(runes[start])
+	}
+	if start > 0 {
+		// 截断时开头用 … 表示，保留文件名所在的末尾
+		for w+1 > width && start < len(runes) {
+			w -= runeWidth(runes[start])
+			start++
+		}
+		return "…" + string(runes[start:]) + strings.Repeat(" ", width-w-1)
+	}
+	return s + strings.Repeat(" ", width-w)
+}
+
+// truncateWidth 把 s 截断到最多 width 个显示宽度，保留开头
+func truncateWidth(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		if w+runeWidth(r) > width {
+			return s[:i]
+		}
+		w += runeWidth(r)
+	}
+	return s
+}
+
+// runeWidth 返回字符的显示宽度
+func runeWidth(r rune) int {
+	if r >= 0x1100 && (r <= 0x115f || r >= 0x2e80 && r <= 0xa4cf || r >= 0xac00 && r <= 0xd7a3 ||
+		r >= 0xf900 && r <= 0xfaff || r >= 0xfe30 && r <= 0xfe4f || r >= 0xff00 && r <= 0xff60 ||
+		r >= 0xffe0 && r <= 0xffe6 || r >= 0x20000 && r <= 0x3fffd) {
+		return 2
+	}
+	return 1
+}
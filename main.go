@@ -1,220 +1,433 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
-	"strconv"
 
 	"runninghub/api"
 )
 
-// 创建结果保存目录
-func createOutputDir() string {
-	// 创建基础目录
-	baseDir := "outputs"
-	if err := os.MkdirAll(baseDir, 0755); err != nil {
-		log.Fatalf("创建输出目录失败: %v", err)
-	}
+// logger 命令行使用的日志器，与 api 包共用
+var logger api.Logger = api.GetLogger()
 
-	// 创建日期子目录
-	dateDir := filepath.Join(baseDir, time.Now().Format("2006-01-02"))
-	if err := os.MkdirAll(dateDir, 0755); err != nil {
-		log.Fatalf("创建日期目录失败: %v", err)
-	}
+// listFlag 可重复指定的列表参数，每次的值也可以用逗号分隔
+type listFlag []string
 
-	return dateDir
-}
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
 
-// 下载文件
-func downloadFile(url, savePath string) error {
-	// 创建文件
-	file, err := os.Create(savePath)
-	if err != nil {
-		return fmt.Errorf("创建文件失败: %v", err)
+func (l *listFlag) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
 	}
-	defer file.Close()
+	return nil
+}
 
-	// 下载文件
-	resp, err := http.Get(url)
+// writeReport 为批量运行生成报告，run 为 nil 表示没有执行任何任务
+func writeReport(run *api.RunRecord, html, markdown bool) {
+	if run == nil || !(html || markdown) {
+		return
+	}
+	paths, err := api.WriteReport(run, markdown)
 	if err != nil {
-		return fmt.Errorf("下载文件失败: %v", err)
+		logger.Error("生成报告失败", "error", err)
+		return
 	}
-	defer resp.Body.Close()
+	for _, p := range paths {
+		if global.json {
+			// -json 模式下标准输出只输出 JSON
+			logger.Info("报告已生成", "path", p)
+		} else {
+			fmt.Printf("报告已生成: %s\n", p)
+		}
+	}
+}
+
+// programName 帮助信息中的程序名
+const programName = "runninghub"
+
+// 进程退出码
+const (
+	exitOK          = 0   // 成功
+	exitFailure     = 1   // 执行失败，批量命令中所有任务都失败
+	exitUsage       = 2   // 命令行参数错误
+	exitPartial     = 3   // 批量命令中部分任务失败
+	exitConfig      = 4   // 配置错误，如配置文件无效、找不到 API Key
+	exitAuth        = 5   // API Key 无效或无权限
+	exitBudget      = 6   // 账户金币不足
+	exitInterrupted = 130 // 被 Ctrl+C 中断
+)
 
-	// 保存文件
-	if _, err := io.Copy(file, resp.Body); err != nil {
-		return fmt.Errorf("保存文件失败: %v", err)
+// configError 加载配置或确定 API Key 时的错误
+type configError struct{ err error }
+
+func (e configError) Error() string { return e.err.Error() }
+func (e configError) Unwrap() error { return e.err }
+
+// exitCodeFor 根据错误确定退出码
+func exitCodeFor(err error) int {
+	var cfgErr configError
+	if errors.As(err, &cfgErr) {
+		return exitConfig
+	}
+	if errors.Is(err, api.ErrTaskCanceled) || errors.Is(err, api.ErrTaskDetached) {
+		return exitInterrupted
 	}
+	switch api.FailureReason(err) {
+	case api.ReasonAuth:
+		return exitAuth
+	case api.ReasonBudget:
+		return exitBudget
+	}
+	return exitFailure
+}
 
-	return nil
+// responseError 把接口返回的错误码和信息转为 error
+func responseError(code int, msg string) error {
+	return fmt.Errorf("code: %d, msg: %s", code, msg)
 }
 
-// 记录任务日志
-func logTaskInfo(dir string, taskID string, outputs []api.TaskOutput) error {
-	logFile := filepath.Join(dir, "task.log")
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("创建日志文件失败: %v", err)
+// command 子命令，run 和 children 只设置其中一个
+type command struct {
+	name     string                                    // 命令名
+	args     string                                    // 用法中命令名之后的部分，如 "<任务ID>"
+	summary  string                                    // 一行说明
+	run      func(fs *flag.FlagSet, args []string) int // 执行命令，fs 已注册全局参数，返回退出码
+	children []*command                                // 子命令组
+}
+
+// commands 所有子命令
+var commands = []*command{
+	runCommand,
+	rerunCommand,
+	batchCommand,
+	taskCommand,
+	workflowsCommand,
+	accountCommand,
+	serveCommand,
+	reportCommand,
+}
+
+// globalOptions 所有命令共用的参数，可以写在子命令之前或之后
+type globalOptions struct {
+	config  string
+	profile string
+	verbose bool
+	quiet   bool
+	json    bool
+}
+
+// global 解析后的全局参数
+var global globalOptions
+
+// register 注册全局参数，默认值为已解析的值，子命令中可以再次覆盖
+func (g *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "config", g.config, "配置文件路径，覆盖默认配置文件中的同名配置")
+	fs.StringVar(&g.profile, "profile", g.profile, "使用配置文件中指定的 profile，多个 profile 用逗号分隔时在这些账户间分配任务")
+	fs.BoolVar(&g.verbose, "v", g.verbose, "输出调试日志（包含请求和响应内容，API Key 会被脱敏）")
+	fs.BoolVar(&g.quiet, "q", g.quiet, "只输出警告和错误日志")
+	fs.BoolVar(&g.json, "json", g.json, "以 JSON 输出结果到标准输出，批量命令每行输出一个事件，日志仍输出到标准错误")
+}
+
+func main() {
+	fs := flag.NewFlagSet(programName, flag.ContinueOnError)
+	global.register(fs)
+	fs.Usage = func() { printGroupUsage(fs.Output(), "", commands) }
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
 	}
-	defer file.Close()
+	setupLogger()
+	os.Exit(dispatch("", commands, fs.Args()))
+}
 
-	// 写入任务信息
-	logger := log.New(file, "", log.LstdFlags)
-	logger.Printf("任务ID: %s", taskID)
-	logger.Printf("执行时间: %s", time.Now().Format("2006-01-02 15:04:05"))
-	logger.Println("生成结果:")
-	for _, output := range outputs {
-		logger.Printf("- 文件URL: %s", output.FileUrl)
-		logger.Printf("  类型: %s", output.FileType)
-		logger.Printf("  节点ID: %s", output.NodeId)
-		logger.Printf("  任务耗时: %s", output.TaskCostTime)
+// dispatch 查找并执行子命令，path 为已匹配的命令路径
+func dispatch(path string, cmds []*command, args []string) int {
+	if len(args) == 0 {
+		printGroupUsage(os.Stderr, path, cmds)
+		return exitUsage
 	}
-	logger.Println("----------------------------------------")
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		// help <命令> 等价于 <命令> -h
+		if len(args) > 1 && name == "help" {
+			return dispatch(path, cmds, append(args[1:], "-h"))
+		}
+		printGroupUsage(os.Stdout, path, cmds)
+		return exitOK
+	}
+	for _, cmd := range cmds {
+		if cmd.name != name {
+			continue
+		}
+		cmdPath := strings.TrimSpace(path + " " + cmd.name)
+		if cmd.children != nil {
+			return dispatch(cmdPath, cmd.children, args[1:])
+		}
+		fs := flag.NewFlagSet(programName+" "+cmdPath, flag.ContinueOnError)
+		fs.Usage = func() {
+			out := fs.Output()
+			fmt.Fprintf(out, "用法: %s %s [参数] %s\n\n%s\n\n参数:\n", programName, cmdPath, cmd.args, cmd.summary)
+			fs.PrintDefaults()
+		}
+		global.register(fs)
+		return cmd.run(fs, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", strings.TrimSpace(path+" "+name))
+	printGroupUsage(os.Stderr, path, cmds)
+	return exitUsage
+}
 
-	return nil
+// printGroupUsage 打印命令组的帮助，顶层命令组同时打印全局参数
+func printGroupUsage(w io.Writer, path string, cmds []*command) {
+	prefix := programName
+	if path != "" {
+		prefix += " " + path
+	}
+	fmt.Fprintf(w, "用法: %s <命令> [参数]\n\n命令:\n", prefix)
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	if path == "" {
+		fmt.Fprintln(w, "\n全局参数（也可以写在命令之后）:")
+		fs := flag.NewFlagSet(programName, flag.ContinueOnError)
+		fs.SetOutput(w)
+		new(globalOptions).register(fs)
+		fs.PrintDefaults()
+	}
+	fmt.Fprintf(w, "\n运行 '%s <命令> -h' 查看命令的参数\n", prefix)
 }
 
-func BatchProcessText(workflowID string, executor *api.WorkflowExecutor) error {
-	// 获取当前工作目录
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("获取工作目录失败: %v", err)
+// parseFlags 解析子命令参数并按 -v/-q 重新设置日志级别，ok 为 false 时应直接返回 code
+// 参数和位置参数可以交替出现，"--" 之后的内容都作为位置参数
+func parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK, false
+			}
+			return exitUsage, false
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
+	// 以 "--" 开头重新解析，使 fs.Args() 返回全部位置参数
+	fs.Parse(append([]string{"--"}, positional...))
+	setupLogger()
+	return exitOK, true
+}
 
-	// 构建文件的绝对路径
-	filePath := filepath.Join(wd, "doc", "book.txt")
-	fmt.Printf("尝试读取文件: %s\n", filePath)
+// workflowArg 从 -workflow 或唯一的位置参数确定工作流ID，ok 为 false 时应直接返回 code
+func workflowArg(fs *flag.FlagSet, workflowID *string) (code int, ok bool) {
+	switch {
+	case fs.NArg() > 1:
+		return usageError(fs, "参数过多: %s", strings.Join(fs.Args()[1:], " ")), false
+	case fs.NArg() == 1 && *workflowID != "":
+		return usageError(fs, "工作流ID不能同时通过 -workflow 和位置参数指定"), false
+	case fs.NArg() == 1:
+		*workflowID = fs.Arg(0)
+	}
+	if *workflowID == "" {
+		return usageError(fs, "必须指定工作流ID"), false
+	}
+	return exitOK, true
+}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("文件不存在: %s", filePath)
+// fileArg 返回唯一的位置参数（配置文件、清单等），ok 为 false 时应直接返回 code
+func fileArg(fs *flag.FlagSet, what string) (path string, code int, ok bool) {
+	if fs.NArg() != 1 {
+		return "", usageError(fs, "必须指定一个%s", what), false
 	}
+	return fs.Arg(0), exitOK, true
+}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("无法打开文件: %v", err)
+// usageError 输出参数错误和帮助提示，返回 exitUsage
+func usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	fmt.Fprintf(os.Stderr, "运行 '%s -h' 查看帮助\n", fs.Name())
+	return exitUsage
+}
+
+// fail 输出错误信息并返回退出码，args 中有 error 时按错误类型确定退出码，否则为 exitFailure
+// -json 模式下向标准输出写入 {"error": "..."}
+func fail(format string, args ...any) int {
+	code := exitFailure
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			code = exitCodeFor(err)
+		}
 	}
-	defer file.Close()
+	msg := fmt.Sprintf(format, args...)
+	if global.json {
+		printJSON(errorDocument{Error: msg})
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	return code
+}
 
-	// 获取文件信息
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("获取文件信息失败: %v", err)
+// errorDocument -json 模式下的错误输出
+type errorDocument struct {
+	Error string `json:"error"`
+}
+
+// taskDocument -json 模式下单个任务的输出，用于 run 和 task 命令
+type taskDocument struct {
+	TaskID     string           `json:"taskId"`
+	WorkflowID string           `json:"workflowId,omitempty"`
+	Status     string           `json:"status,omitempty"` // QUEUED, RUNNING, FAILED, SUCCESS, ERROR, CANCELED
+	Error      string           `json:"error,omitempty"`
+	Outputs    []api.TaskOutput `json:"outputs,omitempty"` // 服务器返回并经筛选规则保留的结果
+	Skipped    int              `json:"skipped,omitempty"` // 被筛选规则过滤掉的输出数量
+	Files      []string         `json:"files,omitempty"`   // 已保存的本地文件
+	Hooks      []api.HookResult `json:"hooks,omitempty"`   // 任务结束后执行的钩子
+}
+
+// monitorFailed 处理 MonitorTask 返回的错误并返回退出码，-json 模式下输出带状态和错误的任务
+func (d *taskDocument) monitorFailed(err error) int {
+	interrupted := errors.Is(err, api.ErrTaskCanceled) || errors.Is(err, api.ErrTaskDetached)
+	if !global.json {
+		if errors.Is(err, api.ErrTaskFailed) || errors.Is(err, api.ErrTaskTimeout) || interrupted {
+			return fail("%v", err)
+		}
+		return fail("监控任务失败: %v", err)
 	}
-	fmt.Printf("文件大小: %d 字节\n", fileInfo.Size())
+	d.Status, d.Error = taskErrorStatus(err), err.Error()
+	printJSON(d)
+	return exitCodeFor(err)
+}
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf("读取文件失败: %v", err)
+// taskErrorStatus 返回 MonitorTask 出错时任务的状态
+func taskErrorStatus(err error) string {
+	switch {
+	case errors.Is(err, api.ErrTaskFailed):
+		return "FAILED"
+	case errors.Is(err, api.ErrTaskTimeout):
+		return api.TaskStatusTimeout
+	case errors.Is(err, api.ErrTaskCanceled):
+		return api.TaskStatusCanceled
+	case errors.Is(err, api.ErrTaskDetached):
+		return api.TaskStatusDetached
 	}
+	return api.TaskStatusError
+}
 
-	if len(content) == 0 {
-		return fmt.Errorf("文件内容为空")
+// stdoutMu 保护并发写入标准输出的 JSON 行
+var stdoutMu sync.Mutex
+
+// printJSON 向标准输出写入一行 JSON
+func printJSON(v interface{}) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		logger.Error("输出 JSON 失败", "error", err)
 	}
+}
 
-	fmt.Printf("读取到的内容长度: %d 字节\n", len(content))
+// jsonEvents -json 模式下批量命令的事件输出，每个任务结束和运行结束时各输出一行，
+// 格式与 Webhook 通知的请求体相同
+type jsonEvents struct{}
 
-	// 统一换行符为 \n，然后按行分割
-	contentStr := strings.ReplaceAll(string(content), "\r\n", "\n")
-	contentStr = strings.ReplaceAll(contentStr, "\r", "\n")
-	paragraphs := strings.Split(contentStr, "\n")
+// TaskFinished 输出 task.succeeded 或 task.failed 事件
+func (jsonEvents) TaskFinished(result *api.TaskResult) {
+	printJSON(api.NewTaskPayload(result))
+}
 
-	outputDir := createOutputDir()
-	fmt.Println("outputDir: ", outputDir)
-	fmt.Println("paragraphs: ", len(paragraphs))
+// RunFinished 输出 run.finished 事件
+func (jsonEvents) RunFinished(run *api.RunRecord) {
+	printJSON(api.NewRunPayload(run))
+}
 
-	// 打印每个段落的内容（用于调试）
-	for i, p := range paragraphs {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
-		}
-		fmt.Printf("段落 %d 长度: %d\n", i+1, len(p))
+// setupLogger 根据 -v/-q 设置日志级别
+func setupLogger() {
+	logger = api.NewLogger(os.Stderr, logLevel())
+	api.SetLogger(logger)
+}
+
+// logLevel 返回 -v/-q 指定的日志级别
+func logLevel() slog.Level {
+	if global.verbose {
+		return slog.LevelDebug
+	} else if global.quiet {
+		return slog.LevelWarn
 	}
+	return slog.LevelInfo
+}
 
-	for idx, para := range paragraphs {
-		para = strings.TrimSpace(para)
-		if para == "" {
-			continue
-		}
-		fmt.Printf("[批量文本] 开始处理第 %d 段: %s\n", idx+1, para)
+// config setupApiKey 加载的配置
+var config *api.Config
 
-		// 执行工作流，使用当前段落作为文本参数
-		resp, err := executor.ExecuteWorkflowWithText(workflowID, para)
-		if err != nil {
-			fmt.Printf("[批量文本] 处理失败: %v\n", err)
-			continue
-		}
-		if resp.Code != 0 || resp.Data.TaskId == "" {
-			fmt.Printf("[批量文本] 任务创建失败: code=%d, msg=%s\n", resp.Code, resp.Msg)
-			continue
-		}
-		fmt.Printf("[批量文本] 任务创建成功: 任务ID: %s\n", resp.Data.TaskId)
-		fmt.Printf("[批量文本] 等待任务完成: 任务ID: %s\n", resp.Data.TaskId)
-
-		err = executor.MonitorTask(resp.Data.TaskId, func(outputResp *api.TaskOutputResponse) {
-			fmt.Printf("[批量文本] 任务完成: 任务ID: %s\n", resp.Data.TaskId)
-			// 保存输出
-			imageBaseName := fmt.Sprintf("text_%d", idx+1)
-			api.SaveTaskOutputs(outputDir, resp.Data.TaskId, outputResp.Data, imageBaseName)
-		})
+// setupApiKey 加载配置并设置 API Key，同时打开任务登记表，访问接口的命令在执行前调用
+func setupApiKey() error {
+	cfg, err := api.LoadConfig(global.config)
+	if err != nil {
+		return configError{fmt.Errorf("加载配置失败: %v", err)}
+	}
+	config = cfg
+	setupRegistry()
+	// 指定了多个 profile 或配置了 pool 时，新任务在多个账户间分配
+	profiles, err := cfg.ResolvePool(global.profile)
+	if err != nil {
+		return configError{err}
+	}
+	if len(profiles) > 0 {
+		pool, err := api.NewKeyPool(profiles)
 		if err != nil {
-			fmt.Printf("[批量文本] 任务监控失败: %v\n", err)
+			return configError{err}
 		}
-		// 顺序执行，等待当前任务完成后再处理下一个
+		api.SetKeyPool(pool)
+		// 未记录账户的任务（如 task 命令查询的任务）使用第一个账户
+		api.SetApiKey(profiles[0].ApiKey)
+		logger.Debug("已加载 API Key", "source", "Key 池中的 profile "+profiles[0].Name)
+		return nil
+	}
+	apiKey, source, err := cfg.ResolveApiKey(global.profile)
+	if err != nil {
+		return configError{err}
 	}
-	fmt.Println("批量文本处理完成。")
+	api.SetApiKey(apiKey)
+	logger.Debug("已加载 API Key", "source", source)
 	return nil
 }
 
-func main() {
-	// 启动时获取并打印账户信息
-	apiKey := api.GetApiKey()
-	if apiKey == "" {
-		fmt.Println("[警告] 未设置 API Key，无法获取账户信息。")
-	} else {
-		status, err := api.GetAccountStatus(apiKey)
-		if err != nil {
-			fmt.Printf("[账户信息] 获取失败: %v\n", err)
-		} else if status.Code != 0 {
-			fmt.Printf("[账户信息] 获取失败: %s\n", status.Msg)
-		} else {
-			remainCoins := status.Data.RemainCoins
-			currentTaskCounts := status.Data.CurrentTaskCounts
-			// 尝试转换为 int
-			if coins, err := strconv.Atoi(remainCoins); err == nil {
-				remainCoins = fmt.Sprintf("%d", coins)
-			}
-			if tasks, err := strconv.Atoi(currentTaskCounts); err == nil {
-				currentTaskCounts = fmt.Sprintf("%d", tasks)
-			}
-			fmt.Printf("[账户信息] 剩余金币: %s，当前任务数: %s\n", remainCoins, currentTaskCounts)
-		}
+// setupRegistry 打开默认的任务登记表，打开失败时不登记任务
+func setupRegistry() {
+	registry, err := api.OpenTaskRegistry(api.DefaultTaskRegistryPath())
+	if err != nil {
+		logger.Warn("无法打开任务登记表，本次创建的任务不会被登记", "error", err)
+		return
 	}
-	time.Sleep(1 * time.Second)
-
-	// 定义命令行参数
-	taskID := flag.String("task", "", "要查询的任务ID")
-	cancel := flag.Bool("cancel", false, "是否取消任务")
-	workflowID := flag.String("workflow", "", "要执行的工作流ID")
-	imagePath := flag.String("image", "", "要上传的图片路径")
-	videoPath := flag.String("video", "", "要上传的视频路径")
-	audioPath := flag.String("audio", "", "要上传的音频路径")
-	list := flag.Bool("list", false, "列出所有可用的工作流")
-	batchImg := flag.Bool("batchImg", false, "批量处理 inputs 目录下的图片")
-	batchText := flag.Bool("batchText", false, "批量处理 inputs 目录下的图片")
-	once := flag.Bool("once", false, "批量处理 inputs 目录下的图片")
-	concurrency := flag.Int("concurrency", 1, "并发数量")
-	flag.Parse()
-
-	// 创建工作流管理器
+	api.SetTaskRegistry(registry)
+}
+
+// newManager 创建工作流管理器并注册工作流
+func newManager() *api.WorkflowManager {
 	manager := api.NewWorkflowManager()
 
 	// 注册工作流
@@ -233,165 +446,260 @@ func main() {
 
 	// 在这里注册更多工作流...
 
-	// 创建工作流执行器
-	executor := api.NewWorkflowExecutor(manager)
+	return manager
+}
 
-	switch {
-	case *batchImg: 
-		if *workflowID == "" {
-			log.Fatalf("批量处理时必须指定 -workflow <工作流ID>")
-		}
-		err := api.BatchProcessInputs(*workflowID, *concurrency, executor)
+// executorOptions 执行任务的命令共用的参数: 输出筛选、结束通知和钩子命令
+type executorOptions struct {
+	keepNodes      string
+	keepTypes      string
+	keepLast       int
+	webhooks       listFlag
+	webhookSecret  string
+	webhookEvents  string
+	webhookRetries int
+	onSuccess      string
+	onFailure      string
+	onBatchDone    string
+	hookTimeout    time.Duration
+	dashboard      bool
+	onInterrupt    string
+	maxQueueTime   time.Duration
+	maxRunTime     time.Duration
+	retry          int
+	retryDelay     time.Duration
+	retryVarySeed  bool
+	retryTimeout   bool
+	seedMode       string
+	seed           string
+}
+
+// register 注册参数，batch 为 true 时注册批量运行结束钩子
+func (o *executorOptions) register(fs *flag.FlagSet, batch bool) {
+	fs.StringVar(&o.keepNodes, "keep-nodes", "", "只下载这些节点的输出，逗号分隔，覆盖工作流配置")
+	fs.StringVar(&o.keepTypes, "keep-types", "", "只下载这些类型的输出，如 mp4 或 image,video，逗号分隔，覆盖工作流配置")
+	fs.IntVar(&o.keepLast, "keep-last", 0, "只下载最后 N 个输出，0 表示不限制，覆盖工作流配置")
+	fs.Var(&o.webhooks, "webhook", "任务结束时发送 JSON 通知的地址，可重复指定或用逗号分隔")
+	fs.StringVar(&o.webhookSecret, "webhook-secret", "", "通知签名密钥，签名放在 X-RunningHub-Signature 请求头")
+	fs.StringVar(&o.webhookEvents, "webhook-events", "", "订阅的事件: task.succeeded,task.failed,run.finished（默认全部）")
+	fs.IntVar(&o.webhookRetries, "webhook-retries", 3, "通知发送失败时的重试次数")
+	fs.StringVar(&o.onSuccess, "on-success", "", "任务成功后执行的命令，任务信息通过 RH_* 环境变量传入")
+	fs.StringVar(&o.onFailure, "on-failure", "", "任务失败后执行的命令，任务信息通过 RH_* 环境变量传入")
+	if batch {
+		fs.StringVar(&o.onBatchDone, "on-batch-done", "", "批量运行结束后执行的命令，运行信息通过 RH_* 环境变量传入")
+		fs.BoolVar(&o.dashboard, "dashboard", false, "在终端中显示进度面板，每个执行中的任务一行；标准输出不是终端时使用普通日志")
+		o.registerSeed(fs)
+		o.registerInterrupt(fs)
+	}
+	fs.DurationVar(&o.hookTimeout, "hook-timeout", 0, "钩子命令的超时时间，0 表示不限制")
+	fs.IntVar(&o.retry, "retry", 0, "任务在服务器上执行失败时最多重新提交的次数，覆盖工作流配置；0 表示使用工作流配置")
+	fs.DurationVar(&o.retryDelay, "retry-delay", 10*time.Second, "与 -retry 一起使用，重新提交前等待的时间")
+	fs.BoolVar(&o.retryVarySeed, "retry-vary-seed", false, "与 -retry 一起使用，重新提交时换用新的随机种子")
+	fs.BoolVar(&o.retryTimeout, "retry-timeout", false, "与 -retry 一起使用，超时取消的任务同样重新提交")
+	fs.DurationVar(&o.maxQueueTime, "max-queue-time", 0, "任务排队超过该时间时取消任务，如 30m，覆盖工作流配置；0 表示使用工作流配置")
+	fs.DurationVar(&o.maxRunTime, "max-run-time", 0, "任务执行超过该时间时取消任务，如 2h，覆盖工作流配置；0 表示使用工作流配置")
+}
+
+// registerSeed 注册随机种子参数
+func (o *executorOptions) registerSeed(fs *flag.FlagSet) {
+	fs.StringVar(&o.seedMode, "seed-mode", "", "随机种子字段的设置方式: fixed（所有任务使用 -seed）, random（每个任务随机）, increment（从 -seed 开始每个任务加 1）；默认使用工作流配置")
+	fs.StringVar(&o.seed, "seed", "", "fixed 模式的种子，increment 模式的起始种子（默认 0）；只指定 -seed 时为 fixed 模式")
+}
+
+// seedPolicy 根据参数返回种子规则，未指定时返回 nil
+func (o *executorOptions) seedPolicy() (*api.SeedPolicy, error) {
+	if o.seedMode == "" && o.seed == "" {
+		return nil, nil
+	}
+	policy := &api.SeedPolicy{Mode: api.SeedFixed}
+	if o.seedMode != "" {
+		mode, err := api.ParseSeedMode(o.seedMode)
 		if err != nil {
-			log.Fatalf("批量处理失败: %v", err)
+			return nil, err
 		}
-		return
-	case *batchText:
-		if *workflowID == "" {
-			log.Fatalf("批量处理时必须指定 -workflow <工作流ID>")
-		}
-		err := BatchProcessText(*workflowID, executor)
-		if err != nil {
-			log.Fatalf("批量文本处理失败: %v", err)
+		policy.Mode = mode
+	}
+	if o.seed != "" {
+		start, err := strconv.ParseInt(o.seed, 10, 64)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("无效的 -seed: %s", o.seed)
 		}
-		return
+		policy.Start = start
+	} else if policy.Mode == api.SeedFixed {
+		return nil, fmt.Errorf("fixed 模式需要用 -seed 指定种子")
+	}
+	return policy, nil
+}
 
-	case *once:
-		if *workflowID == "" {
-			log.Fatalf("单次处理时必须指定 -workflow <工作流ID>")
-		}
-		var resp *api.TaskCreateResponse
-		var err error
-
-		var imageBaseName string
-		if *videoPath != "" && *audioPath != "" {
-			// 执行带视频和音频的工作流
-			resp, err = executor.ExecuteWorkflowWithVideoAndAudio(*workflowID, *videoPath, *audioPath)
-			// 使用视频文件名作为基础名
-			base := filepath.Base(*videoPath)
-			imageBaseName = strings.TrimSuffix(base, filepath.Ext(base))
-		} else if *imagePath != "" {
-			// 获取图片基础名（不含扩展名）
-			base := filepath.Base(*imagePath)
-			imageBaseName = strings.TrimSuffix(base, filepath.Ext(base))
-			// 执行带图片的工作流
-			resp, err = executor.ExecuteWorkflowWithImage(*workflowID, *imagePath)
-		} else {
-			// 执行普通工作流
-			resp, err = executor.ExecuteWorkflow(*workflowID)
+// registerInterrupt 注册中断策略参数，注册后 newExecutor 会处理 Ctrl+C
+func (o *executorOptions) registerInterrupt(fs *flag.FlagSet) {
+	fs.StringVar(&o.onInterrupt, "on-interrupt", string(api.InterruptDetach),
+		"按 Ctrl+C 时执行中任务的处理方式: detach（停止等待，任务ID保存在运行记录中）, cancel（取消任务）, wait（等待任务结束）")
+}
+
+// newExecutor 设置 API Key 并创建工作流执行器
+func (o *executorOptions) newExecutor() (*api.WorkflowExecutor, error) {
+	events, err := api.ParseWebhookEvents(o.webhookEvents)
+	if err != nil {
+		return nil, configError{fmt.Errorf("参数错误: %v", err)}
+	}
+	var policy api.InterruptPolicy
+	if o.onInterrupt != "" {
+		if policy, err = api.ParseInterruptPolicy(o.onInterrupt); err != nil {
+			return nil, configError{fmt.Errorf("参数错误: %v", err)}
 		}
+	}
+	seed, err := o.seedPolicy()
+	if err != nil {
+		return nil, configError{fmt.Errorf("参数错误: %v", err)}
+	}
+	if err := setupApiKey(); err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			log.Fatalf("执行工作流失败: %v", err)
+	executor := api.NewWorkflowExecutor(newManager())
+	if seed != nil {
+		executor.SetSeedPolicy(seed)
+	}
+	executor.SetOutputSelector(api.ParseOutputSelector(o.keepNodes, o.keepTypes, o.keepLast))
+	if o.retry > 0 {
+		executor.SetRetryPolicy(&api.RetryPolicy{Attempts: o.retry, Delay: o.retryDelay, VarySeed: o.retryVarySeed, OnTimeout: o.retryTimeout})
+	}
+	if o.maxQueueTime > 0 || o.maxRunTime > 0 {
+		executor.SetTaskTimeout(&api.TaskTimeout{MaxQueueTime: o.maxQueueTime, MaxRunTime: o.maxRunTime})
+	}
+	if o.onSuccess != "" || o.onFailure != "" || o.onBatchDone != "" {
+		executor.AddNotifier(&api.Hooks{OnSuccess: o.onSuccess, OnFailure: o.onFailure, OnBatchDone: o.onBatchDone, Timeout: o.hookTimeout})
+	}
+	for _, url := range o.webhooks {
+		executor.AddNotifier(&api.Webhook{URL: url, Secret: o.webhookSecret, Events: events, Retries: o.webhookRetries})
+	}
+	if global.json {
+		executor.AddNotifier(jsonEvents{})
+	} else if o.dashboard && isTerminal(os.Stdout) {
+		// 面板模式下日志显示在面板上方，未指定 -v 时只显示警告和错误
+		d := newDashboard(os.Stdout)
+		level := logLevel()
+		if level == slog.LevelInfo {
+			level = slog.LevelWarn
 		}
+		logger = api.NewLogger(d, level)
+		api.SetLogger(logger)
+		executor.AddNotifier(d)
+	}
+	if policy != "" {
+		handleInterrupt(executor, policy)
+	}
+	return executor, nil
+}
 
-		// 检查任务创建是否成功
-		if resp.Code != 0 || resp.Data.TaskId == "" {
-			log.Fatalf("任务创建失败！code: %d, msg: %s", resp.Code, resp.Msg)
+// handleInterrupt 第一次收到 Ctrl+C（或 SIGTERM）时中断执行器上的运行，不再提交新任务，执行中的任务按 policy 处理；
+// 第二次收到时立即退出
+func handleInterrupt(executor *api.WorkflowExecutor, policy api.InterruptPolicy) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		actions := map[api.InterruptPolicy]string{
+			api.InterruptDetach: "停止等待执行中的任务（任务仍在服务器上执行）",
+			api.InterruptCancel: "取消执行中的任务",
+			api.InterruptWait:   "等待执行中的任务结束",
 		}
+		logger.Warn("收到中断信号，不再提交新任务，"+actions[policy]+"；再按一次 Ctrl+C 立即退出", "policy", policy)
+		executor.Interrupt(policy)
+		<-signals
+		fmt.Fprintln(os.Stderr, "强制退出，执行中的任务可能仍在服务器上执行")
+		os.Exit(exitInterrupted)
+	}()
+}
 
-		fmt.Printf("任务创建成功！任务ID: %s\n", resp.Data.TaskId)
-		fmt.Println("正在等待任务完成...")
-
-		// 创建输出目录
-		outputDir := createOutputDir()
-
-		// 自动监控任务状态并显示结果
-		err = executor.MonitorTask(resp.Data.TaskId, func(outputResp *api.TaskOutputResponse) {
-			fmt.Println("\n任务执行成功！")
-			fmt.Println("生成结果:")
-			timestamp := time.Now().Format("20060102_150405")
-			for i, output := range outputResp.Data {
-				fmt.Printf("- 文件URL: %s\n", output.FileUrl)
-				fmt.Printf("  类型: %s\n", output.FileType)
-				fmt.Printf("  节点ID: %s\n", output.NodeId)
-				fmt.Printf("  任务耗时: %s\n", output.TaskCostTime)
-
-				// 判断是否为视频类型，若是则用图片名命名
-				var fileName string
-				if imageBaseName != "" {
-					fileName = fmt.Sprintf("%s_%s_%d%s", imageBaseName, timestamp, i, filepath.Ext(output.FileUrl))
-				} else {
-					fileName = fmt.Sprintf("%s_%s_%d%s", resp.Data.TaskId, timestamp, i, filepath.Ext(output.FileUrl))
-				}
-				savePath := filepath.Join(outputDir, fileName)
-				if err := downloadFile(output.FileUrl, savePath); err != nil {
-					log.Printf("下载文件失败: %v", err)
-					continue
-				}
-				fmt.Printf("  已保存到: %s\n", savePath)
-			}
+// reportOptions 批量命令的报告参数
+type reportOptions struct {
+	html     bool
+	markdown bool
+}
 
-			// 记录任务日志
-			if err := logTaskInfo(outputDir, resp.Data.TaskId, outputResp.Data); err != nil {
-				log.Printf("记录任务日志失败: %v", err)
-			}
-		})
-		if err != nil {
-			log.Fatalf("监控任务失败: %v", err)
-		}
-		return
+// register 注册参数
+func (o *reportOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.html, "report", false, "批量处理结束后在输出目录生成 HTML 报告")
+	fs.BoolVar(&o.markdown, "report-md", false, "生成报告时同时生成 Markdown 摘要")
+}
 
-	case *taskID != "":
-		if *cancel {
-			// 取消任务
-			resp, err := api.CancelTask(*taskID)
-			if err != nil {
-				log.Fatalf("取消任务失败: %v", err)
-			}
-			fmt.Printf("取消任务响应: %+v\n", resp)
-			return
-		}
+// finish 按参数为批量运行生成报告，打印运行摘要，并根据任务结果返回退出码
+func (o *reportOptions) finish(run *api.RunRecord) int {
+	if run == nil {
+		return exitOK
+	}
+	writeReport(run, o.html, o.markdown)
+	stats := run.Stats()
+	if !global.json {
+		printRunSummary(run, stats)
+		printDetached(run, stats)
+	}
+	return runExitCode(run, stats)
+}
 
-		// 监控任务状态
-		err := executor.MonitorTask(*taskID, func(outputResp *api.TaskOutputResponse) {
-			fmt.Println("\n任务执行成功！")
-			fmt.Println("生成结果:")
-			for _, output := range outputResp.Data {
-				fmt.Printf("- 文件URL: %s\n", output.FileUrl)
-				fmt.Printf("  类型: %s\n", output.FileType)
-				fmt.Printf("  节点ID: %s\n", output.NodeId)
-				fmt.Printf("  任务耗时: %s\n", output.TaskCostTime)
-			}
-		})
-		if err != nil {
-			log.Fatalf("监控任务失败: %v", err)
-		}
-		return
+// printRunSummary 打印批量运行的摘要表格
+func printRunSummary(run *api.RunRecord, stats api.RunStats) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "任务总数\t%d\n", stats.Total)
+	fmt.Fprintf(w, "成功\t%d\n", stats.Succeeded)
+	fmt.Fprintf(w, "失败\t%d\n", stats.Failed)
+	if stats.TimedOut > 0 {
+		fmt.Fprintf(w, "  其中超时\t%d\n", stats.TimedOut)
+	}
+	fmt.Fprintf(w, "已取消\t%d\n", stats.Canceled)
+	fmt.Fprintf(w, "已跳过\t%d\n", stats.Skipped)
+	if stats.Detached > 0 {
+		fmt.Fprintf(w, "未等待结束\t%d\n", stats.Detached)
+	}
+	fmt.Fprintf(w, "总耗时\t%s\n", run.Duration().Round(time.Second))
+	fmt.Fprintf(w, "消耗金币\t%v\n", stats.Coins)
+	fmt.Fprintf(w, "下载文件\t%d\n", stats.Files)
+	fmt.Fprintf(w, "运行记录\t%s\n", run.Path())
+	w.Flush()
+}
 
-	case *list:
-		workflows := manager.ListWorkflows()
-		fmt.Println("可用的工作流:")
-		for _, wf := range workflows {
-			fmt.Printf("\n工作流ID: %s\n", wf.ID)
-			fmt.Printf("名称: %s\n", wf.Name)
-			fmt.Printf("描述: %s\n", wf.Description)
-			fmt.Println("节点配置:")
-			for _, param := range wf.Params {
-				if param.IsImage {
-					fmt.Printf("  - 图片输入节点: %s\n", param.NodeId)
-				} else {
-					fmt.Printf("  - 节点: %s, 字段: %s, 值: %s\n", param.NodeId, param.FieldName, param.FieldValue)
-				}
-			}
-		}
+// printDetached 列出运行中断时停止等待、仍在服务器上执行的任务
+func printDetached(run *api.RunRecord, stats api.RunStats) {
+	if stats.Detached == 0 {
 		return
+	}
+	fmt.Println("\n以下任务仍在服务器上执行:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, task := range run.Tasks {
+		if task != nil && task.Status == api.TaskStatusDetached {
+			fmt.Fprintf(w, "  %s\t%s\n", task.TaskID, task.Label)
+		}
+	}
+	w.Flush()
+	fmt.Printf("运行 '%s batch resume %s' 继续等待并下载结果，或 '%s task cancel <任务ID>' 取消任务\n", programName, run.Path(), programName)
+}
 
+// runExitCode 根据批量运行的任务结果确定退出码
+// 运行被中断时返回 exitInterrupted；有任务因 API Key 无效或金币不足失败时优先返回对应的退出码
+func runExitCode(run *api.RunRecord, stats api.RunStats) int {
+	if run.Interrupted {
+		return exitInterrupted
+	}
+	for _, task := range run.Tasks {
+		if task == nil {
+			continue
+		}
+		switch task.Reason {
+		case api.ReasonAuth:
+			return exitAuth
+		case api.ReasonBudget:
+			return exitBudget
+		}
+	}
+	switch {
+	case stats.Succeeded == stats.Total:
+		return exitOK
+	case stats.Succeeded == 0:
+		return exitFailure
 	default:
-		fmt.Println("使用方法:")
-		fmt.Println("1. 列出所有工作流:")
-		fmt.Println("   go run main.go -list")
-		fmt.Println("\n2. 执行工作流:")
-		fmt.Println("   go run main.go -workflow <工作流ID>")
-		fmt.Println("\n3. 执行带图片的工作流:")
-		fmt.Println("   go run main.go -workflow <工作流ID> -image <图片路径>")
-		fmt.Println("\n4. 执行对口型工作流:")
-		fmt.Println("   go run main.go -workflow <工作流ID> -video <视频路径> -audio <音频路径>")
-		fmt.Println("\n5. 查询任务状态:")
-		fmt.Println("   go run main.go -task <任务ID>")
-		fmt.Println("\n6. 取消任务:")
-		fmt.Println("   go run main.go -task <任务ID> -cancel")
-		fmt.Println("\n7. 批量处理:")
-		fmt.Println("   go run main.go -batch -workflow <工作流ID> [-concurrency N]")
+		return exitPartial
 	}
 }
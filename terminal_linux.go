@@ -0,0 +1,24 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalSize 终端窗口大小，对应 struct winsize
+type terminalSize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// ttyColumns 返回终端的列数，不是终端或查询失败时返回 0
+func ttyColumns(f *os.File) int {
+	var size terminalSize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+	return int(size.cols)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// ttyColumns 当前平台不查询终端大小，返回 0 表示未知
+func ttyColumns(f *os.File) int {
+	return 0
+}